@@ -16,19 +16,26 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/showbufire/gc6/common"
 	"github.com/showbufire/gc6/mazelib"
+	"github.com/showbufire/gc6/stats"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/net/websocket"
 )
 
 type Maze struct {
@@ -37,16 +44,16 @@ type Maze struct {
 	end        mazelib.Coordinate
 	icarus     mazelib.Coordinate
 	StepsTaken int
+	// optimal is the length of the shortest path from the start to the treasure
+	optimal int
+	// seed the maze was generated from
+	seed int64
+	// maxSteps is the step budget, zero means there's no limit
+	maxSteps int
 }
 
-// Tracking the current maze being solved
-
-// WARNING: This approach is not safe for concurrent use
-// This server is only intended to have a single client at a time
-// We would need a different and more complex approach if we wanted
-// concurrent connections than these simple package variables
-var currentMaze *Maze
-var scores []int
+// Tracking the mazes being solved, one per Icarus
+var sessions *sessionStore
 
 const (
 	cutLimit = 3
@@ -61,30 +68,37 @@ var daedalusCmd = &cobra.Command{
 	Long: `Daedalus's job is to create a challenging Labyrinth for his opponent
   Icarus to solve.
 
-  Daedalus runs a server which Icarus clients can connect to to solve laybrinths.`,
+  Daedalus runs a server which Icarus clients can connect to to solve laybrinths.
+  Open /viewer in a browser to watch them, or /viewer?session=<id> to follow
+  a single Icarus.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		RunServer()
+		if err := RunServer(interruptContext(), nil, false); err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
 	},
 }
 
 func init() {
-	rand.Seed(time.Now().UTC().UnixNano()) // need to initialize the seed
 	gin.SetMode(gin.ReleaseMode)
 
 	RootCmd.AddCommand(daedalusCmd)
 }
 
-// Runs the web server
-func RunServer() {
-	// Adding handling so that even when ctrl+c is pressed we still print
-	// out the results prior to exiting.
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		<-c
-		printResults()
-		os.Exit(1)
-	}()
+// shutdownTimeout is how long in-flight requests have to finish once the server stops
+const shutdownTimeout = 5 * time.Second
+
+// Runs the web server until the context is canceled, or until the last Icarus is done
+// when untilDone is set. If ready isn't nil, it's closed as soon as the server accepts connections.
+// The results are printed before it returns.
+func RunServer(ctx context.Context, ready chan<- struct{}, untilDone bool) error {
+	if err := checkMazeSource(); err != nil {
+		return err
+	}
+
+	sessions = newSessionStore(viper.GetDuration("session-timeout"), untilDone)
+	go sessions.reap()
+	defer sessions.finish()
 
 	// Using gin-gonic/gin to handle our routing
 	r := gin.Default()
@@ -92,83 +106,414 @@ func RunServer() {
 	{
 		v1.GET("/awake", GetStartingPoint)
 		v1.GET("/move/:direction", MoveDirection)
+		v1.POST("/moves", MoveDirections)
 		v1.GET("/done", End)
+		v1.GET("/healthz", Healthz)
+		v1.GET("/ws", gin.WrapH(websocket.Server{Handler: MoveSocket}))
+		v1.GET("/events", Events)
+		v1.GET("/viewer", Viewer)
+	}
+
+	srv := &http.Server{Addr: ":" + viper.GetString("port"), Handler: r}
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	if ready != nil {
+		close(ready)
 	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
 
-	r.Run(":" + viper.GetString("port"))
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	case <-sessions.finished:
+	}
+
+	// Even when ctrl+c is pressed we still print out the results.
+	// The viewers are let go first, the server waits for every connection to close.
+	sessions.events.close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	writeResults(sessions.results())
+	return err
+}
+
+// checkMazeSource makes sure mazes can be made, before any Icarus asks for one
+func checkMazeSource() error {
+	if _, err := mazelib.GetGenerator(viper.GetString("generator")); err != nil {
+		return err
+	}
+	if difficulty := viper.GetString("difficulty"); difficulty != "" {
+		if _, err := mazelib.ParseBand(difficulty); err != nil {
+			return err
+		}
+	}
+	if dir := viper.GetString("maze-dir"); dir != "" {
+		if _, err := mazeFiles(dir); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Ends a session and prints the results.
 // Called by Icarus when he has reached
 //   the number of times he wants to solve the laybrinth.
+// When told to, the server stops once the last session is done, after replying.
 func End(c *gin.Context) {
-	printResults()
-	os.Exit(1)
+	r, status, last := sessions.end(c.Query("session"))
+	c.JSON(status, r)
+	if last {
+		sessions.finish()
+	}
+}
+
+// Tells that the server is up, so Icarus knows when he can awake
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, mazelib.Reply{Message: "ok"})
 }
 
 // initializes a new maze and places Icarus in his awakening location
 func GetStartingPoint(c *gin.Context) {
-	initializeMaze()
-	startRoom, err := currentMaze.Discover(currentMaze.Icarus())
-	if err != nil {
-		fmt.Println("Icarus is outside of the maze. This shouldn't ever happen")
-		fmt.Println(err)
-		os.Exit(-1)
-	}
-	mazelib.PrintMaze(currentMaze)
-
-	c.JSON(http.StatusOK, mazelib.Reply{Survey: startRoom})
+	r, status := sessions.awake(c.Query("session"), c.Query("seed"))
+	c.JSON(status, r)
 }
 
 // The API response to the /move/:direction address
 func MoveDirection(c *gin.Context) {
-	var err error
+	r, status := sessions.move(c.Query("session"), c.Param("direction"))
+	c.JSON(status, r)
+}
+
+// movesRequest is the body of a POST to /moves
+type movesRequest struct {
+	Directions []string `json:"directions"`
+}
 
-	switch c.Param("direction") {
-	case "left":
-		err = currentMaze.MoveLeft()
-	case "right":
-		err = currentMaze.MoveRight()
-	case "down":
-		err = currentMaze.MoveDown()
-	case "up":
-		err = currentMaze.MoveUp()
+// The API response to a POST to the /moves address, which makes many moves at once
+func MoveDirections(c *gin.Context) {
+	var req movesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mazelib.Reply{Error: true, Message: err.Error(), Session: c.Query("session")})
+		return
 	}
+	r, status := sessions.moves(c.Query("session"), req.Directions)
+	c.JSON(status, r)
+}
 
-	var r mazelib.Reply
+// The logic behind the handlers lives in the session store, so that Icarus can also
+// reach it without a server. Every call returns the reply and its HTTP status.
 
+// end removes a session and prints its results.
+// It also tells whether that was the last session the store waited for,
+// then the caller prints the overall results instead.
+func (s *sessionStore) end(id string) (mazelib.Reply, int, bool) {
+	sess, r, ok := s.lookup(id)
+	if !ok {
+		return r, http.StatusNotFound, false
+	}
+	sess.Lock()
+	defer sess.Unlock()
+
+	s.recordMoves(sess)
+	left := s.remove(sess.id)
+	s.events.publish(event{Type: eventEnd, Session: sess.id, Round: sess.round})
+	last := left == 0 && s.untilDone
+	if !last {
+		printResults(os.Stdout, sess.scores, sess.failures)
+	}
+	return mazelib.Reply{Session: sess.id}, http.StatusOK, last
+}
+
+// awake initializes a new maze and places Icarus in his awakening location.
+// A new session is started, unless Icarus gives the one he already has.
+// Icarus may ask for the seed of the maze, to replay it.
+func (s *sessionStore) awake(id, seed string) (mazelib.Reply, int) {
+	var sess *session
+	if id == "" {
+		var err error
+		if sess, err = s.create(); err != nil {
+			return mazelib.Reply{Error: true, Message: err.Error()}, http.StatusInternalServerError
+		}
+	} else {
+		var r mazelib.Reply
+		var ok bool
+		if sess, r, ok = s.lookup(id); !ok {
+			return r, http.StatusNotFound
+		}
+	}
+
+	sess.Lock()
+	defer sess.Unlock()
+
+	var err error
+	var m *Maze
+	if dir := viper.GetString("maze-dir"); dir != "" {
+		m, err = loadNextMaze(dir)
+	} else {
+		var n int64
+		if n, err = awakeSeed(seed); err != nil {
+			return mazelib.Reply{Error: true, Message: err.Error(), Session: sess.id}, http.StatusBadRequest
+		}
+		m, err = generateMaze(n)
+	}
+	if err != nil {
+		return mazelib.Reply{Error: true, Message: err.Error(), Session: sess.id}, http.StatusInternalServerError
+	}
+	s.recordMoves(sess)
+	sess.maze = m
+	sess.failed = false
+	sess.round++
+	sess.route = []mazelib.Coordinate{m.icarus}
+	sess.moves = nil
+	sess.recorded = false
+	startRoom, err := sess.maze.Discover(sess.maze.Icarus())
 	if err != nil {
+		// Icarus is outside of the maze. This shouldn't ever happen
+		return mazelib.Reply{Error: true, Message: err.Error(), Session: sess.id}, http.StatusInternalServerError
+	}
+	// the maze is only shown for whoever runs daedalus, a broken stdout mustn't stop the server
+	if err := mazelib.WriteASCII(os.Stdout, sess.maze); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if s.events.watched(sess.id) {
+		if e, err := sess.mazeEvent(); err == nil {
+			s.events.publish(e)
+		}
+	}
+
+	return mazelib.Reply{Survey: startRoom, Session: sess.id, Seed: m.seed}, http.StatusOK
+}
+
+// move takes Icarus one step in the direction: left, right, up or down
+func (s *sessionStore) move(id, direction string) (mazelib.Reply, int) {
+	sess, r, ok := s.lookup(id)
+	if !ok {
+		return r, http.StatusNotFound
+	}
+
+	sess.Lock()
+	defer sess.Unlock()
+	return s.step(sess, direction)
+}
+
+// moves takes Icarus through the directions in order, stopping at the first move refused,
+// or at the treasure. The reply has the survey of every room he entered.
+func (s *sessionStore) moves(id string, directions []string) (mazelib.Reply, int) {
+	sess, r, ok := s.lookup(id)
+	if !ok {
+		return r, http.StatusNotFound
+	}
+	r.Session = sess.id
+	if len(directions) == 0 {
+		r.Error = true
+		r.Message = "no move to make"
+		return r, http.StatusBadRequest
+	}
+	// a batch is refused as a whole, rather than half made
+	for _, direction := range directions {
+		if !validDirection(direction) {
+			r.Error = true
+			r.Message = "unknown direction " + direction
+			return r, http.StatusBadRequest
+		}
+	}
+
+	sess.Lock()
+	defer sess.Unlock()
+
+	surveys := []mazelib.Survey{}
+	for _, direction := range directions {
+		var status int
+		if r, status = s.step(sess, direction); status != http.StatusOK {
+			r.Surveys = surveys
+			return r, status
+		}
+		surveys = append(surveys, r.Survey)
+		if r.Victory || r.Error {
+			break
+		}
+	}
+	r.Surveys = surveys
+	return r, http.StatusOK
+}
+
+// validDirection tells whether Icarus can take a step in the direction
+func validDirection(direction string) bool {
+	_, ok := moveFuncs[direction]
+	return ok
+}
+
+// step takes Icarus one step in the direction. The session must be locked.
+// Unknown directions are refused the same way, whichever endpoint they came from.
+func (s *sessionStore) step(sess *session, direction string) (mazelib.Reply, int) {
+	r := mazelib.Reply{Session: sess.id}
+
+	if sess.maze == nil {
+		r.Error = true
+		r.Message = "Icarus hasn't awaken yet"
+		return r, 409
+	}
+
+	if err := sess.maze.move(direction); err != nil {
+		if err == mazelib.ErrUnknownDirection {
+			r.Error = true
+			r.Message = "unknown direction " + direction
+			return r, http.StatusBadRequest
+		}
+		if err == mazelib.ErrMaxSteps {
+			if !sess.failed {
+				s.fail(sess)
+			}
+			r.Exhausted = true
+			e := sess.moveEvent()
+			e.Exhausted = true
+			s.events.publish(e)
+			s.recordMoves(sess)
+		}
+		// Icarus stays where he was, and sees it again
+		r.Survey, _ = sess.maze.Discover(sess.maze.Icarus())
 		r.Error = true
 		r.Message = err.Error()
-		c.JSON(409, r)
-		return
+		return r, 409
 	}
 
-	s, e := currentMaze.LookAround()
+	survey, e := sess.maze.LookAround()
 
 	if e != nil {
 		if e == mazelib.ErrVictory {
-			scores = append(scores, currentMaze.StepsTaken)
+			s.record(sess, stats.Run{Steps: sess.maze.StepsTaken, Optimal: sess.maze.optimal})
 			r.Victory = true
-			r.Message = fmt.Sprintf("Victory achieved in %d steps \n", currentMaze.StepsTaken)
+			r.Steps = sess.maze.StepsTaken
+			r.Optimal = sess.maze.optimal
+			r.Message = fmt.Sprintf("Victory achieved in %d steps, the shortest path has %d steps \n", r.Steps, r.Optimal)
+			fmt.Print(r.Message)
 		} else {
 			r.Error = true
-			r.Message = err.Error()
+			r.Message = e.Error()
 		}
 	}
 
-	r.Survey = s
+	sess.route = append(sess.route, sess.maze.icarus)
+	sess.moves = append(sess.moves, direction)
+	moved := sess.moveEvent()
+	moved.Victory = r.Victory
+	s.events.publish(moved)
+	if r.Victory {
+		s.recordMoves(sess)
+	}
+
+	r.Survey = survey
 
-	c.JSON(http.StatusOK, r)
+	return r, http.StatusOK
+}
+
+// recordMoves saves the moves Icarus made in the maze of the session to --record-dir, if the user asks to.
+// It's called once he's done with the maze, the moves are only saved the first time.
+func (s *sessionStore) recordMoves(sess *session) {
+	dir := viper.GetString("record-dir")
+	if dir == "" || sess.maze == nil || sess.recorded {
+		return
+	}
+	sess.recorded = true
+	path := filepath.Join(dir, fmt.Sprintf("maze-%d-%.8s-%d.moves", sess.maze.seed, sess.id, sess.round))
+	if err := saveMoves(path, moveRecord{Seed: sess.maze.seed, Moves: sess.moves}); err != nil {
+		fmt.Println(err)
+	}
 }
 
-func initializeMaze() {
-	currentMaze = createMaze()
+// lookup finds a session. If there isn't one, it returns the reply telling so.
+func (s *sessionStore) lookup(id string) (*session, mazelib.Reply, bool) {
+	sess, ok := s.get(id)
+	if !ok {
+		return nil, mazelib.Reply{Error: true, Message: "unknown session " + id}, false
+	}
+	return sess, mazelib.Reply{}, true
 }
 
-// Print to the terminal the average steps to solution for the current session
-func printResults() {
-	fmt.Printf("Labyrinth solved %d times with an avg of %d steps\n", len(scores), mazelib.AvgScores(scores))
+// mazeCount numbers the mazes served, so that mazes following the --seed differ
+var mazeCount int64
+
+// generateMaze creates a maze from the seed, and saves it if the user asks to
+func generateMaze(seed int64) (*Maze, error) {
+	m, err := createMaze(seed)
+	if err != nil {
+		return nil, err
+	}
+	if dir := viper.GetString("save-dir"); dir != "" {
+		if err := saveMaze(filepath.Join(dir, fmt.Sprintf("maze-%d.json", seed)), m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// loadNextMaze serves the saved mazes of a directory one after the other, in name order
+func loadNextMaze(dir string) (*Maze, error) {
+	files, err := mazeFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	n := atomic.AddInt64(&mazeCount, 1) - 1
+	m, err := loadMaze(files[n%int64(len(files))])
+	if err != nil {
+		return nil, err
+	}
+	m.maxSteps = viper.GetInt("max-steps")
+	return m, nil
+}
+
+// awakeSeed picks the seed of a new maze. Icarus can ask for a seed, to replay a maze.
+// Otherwise the n-th maze uses --seed plus n, or the clock if there is no --seed.
+func awakeSeed(asked string) (int64, error) {
+	if asked != "" {
+		return strconv.ParseInt(asked, 10, 64)
+	}
+	n := atomic.AddInt64(&mazeCount, 1) - 1
+	if seed := viper.GetInt64("seed"); seed != 0 {
+		return seed + n, nil
+	}
+	return time.Now().UnixNano(), nil
+}
+
+// Print the statistics of the steps to solution for the given scores,
+// and how many times Icarus ran out of steps
+func printResults(w io.Writer, scores []stats.Run, failures int) {
+	summary := stats.Summarize(scores, failures)
+	var err error
+	if viper.GetString("results-format") == "json" {
+		err = summary.WriteJSON(w)
+	} else {
+		err = summary.WriteTable(w)
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// writeResults prints the overall results, to --results-file if there is one,
+// so they aren't mixed with what daedalus and icarus print along the way
+func writeResults(scores []stats.Run, failures int) {
+	path := viper.GetString("results-file")
+	if path == "" {
+		printResults(os.Stdout, scores, failures)
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	printResults(f, scores, failures)
+	if err := f.Close(); err != nil {
+		fmt.Println(err)
+	}
 }
 
 // Return a room from the maze
@@ -201,6 +546,7 @@ func (m *Maze) SetStartPoint(x, y int) error {
 	}
 
 	r.Start = true
+	m.start = mazelib.Coordinate{x, y}
 	m.icarus = mazelib.Coordinate{x, y}
 	return nil
 }
@@ -226,7 +572,6 @@ func (m *Maze) SetTreasure(x, y int) error {
 // Will return ErrVictory if Icarus is at the treasure.
 func (m *Maze) LookAround() (mazelib.Survey, error) {
 	if m.end.X == m.icarus.X && m.end.Y == m.icarus.Y {
-		fmt.Printf("Victory achieved in %d steps \n", m.StepsTaken)
 		return mazelib.Survey{}, mazelib.ErrVictory
 	}
 
@@ -243,6 +588,29 @@ func (m *Maze) Discover(x, y int) (mazelib.Survey, error) {
 	}
 }
 
+// move takes Icarus one step in the direction: left, right, up or down.
+// Other directions are refused with ErrUnknownDirection.
+func (m *Maze) move(direction string) error {
+	move, ok := moveFuncs[direction]
+	if !ok {
+		return mazelib.ErrUnknownDirection
+	}
+	return move(m)
+}
+
+// moveFuncs take Icarus one step, by direction
+var moveFuncs = map[string]func(m *Maze) error{
+	"left":  (*Maze).MoveLeft,
+	"right": (*Maze).MoveRight,
+	"down":  (*Maze).MoveDown,
+	"up":    (*Maze).MoveUp,
+}
+
+// outOfSteps tells whether Icarus has spent all of his steps
+func (m *Maze) outOfSteps() bool {
+	return m.maxSteps > 0 && m.StepsTaken >= m.maxSteps
+}
+
 // Moves Icarus's position left one step
 // Will not permit moving through walls or out of the maze
 func (m *Maze) MoveLeft() error {
@@ -250,6 +618,9 @@ func (m *Maze) MoveLeft() error {
 	if e != nil {
 		return e
 	}
+	if m.outOfSteps() {
+		return mazelib.ErrMaxSteps
+	}
 	if s.Left {
 		return errors.New("Can't walk through walls")
 	}
@@ -271,6 +642,9 @@ func (m *Maze) MoveRight() error {
 	if e != nil {
 		return e
 	}
+	if m.outOfSteps() {
+		return mazelib.ErrMaxSteps
+	}
 	if s.Right {
 		return errors.New("Can't walk through walls")
 	}
@@ -292,6 +666,9 @@ func (m *Maze) MoveUp() error {
 	if e != nil {
 		return e
 	}
+	if m.outOfSteps() {
+		return mazelib.ErrMaxSteps
+	}
 	if s.Top {
 		return errors.New("Can't walk through walls")
 	}
@@ -313,6 +690,9 @@ func (m *Maze) MoveDown() error {
 	if e != nil {
 		return e
 	}
+	if m.outOfSteps() {
+		return mazelib.ErrMaxSteps
+	}
 	if s.Bottom {
 		return errors.New("Can't walk through walls")
 	}
@@ -327,6 +707,15 @@ func (m *Maze) MoveDown() error {
 	return nil
 }
 
+// shortestPath is the number of steps from the start to the treasure, zero if there's no way
+func (m *Maze) shortestPath() int {
+	dist, err := mazelib.Distances(m, m.start)
+	if err != nil {
+		return 0
+	}
+	return dist[m.end]
+}
+
 // Creates a maze without any walls
 // Good starting point for additive algorithms
 func emptyMaze() *Maze {
@@ -349,16 +738,17 @@ func emptyMaze() *Maze {
 // Good starting point for subtractive algorithms
 func fullMaze() *Maze {
 	z := emptyMaze()
-	ySize := viper.GetInt("height")
-	xSize := viper.GetInt("width")
+	z.addAllWalls()
+	return z
+}
 
-	for y := 0; y < ySize; y++ {
-		for x := 0; x < xSize; x++ {
-			z.rooms[y][x].Walls = mazelib.Survey{true, true, true, true}
+// addAllWalls surrounds every room with walls, turning the maze into a full one in place
+func (m *Maze) addAllWalls() {
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			m.rooms[y][x].Walls = mazelib.Survey{true, true, true, true}
 		}
 	}
-
-	return z
 }
 
 // addBoundary adds the outer boundary
@@ -378,11 +768,11 @@ func (m *Maze) addBoundary() {
 }
 
 // findNaiveRoute finds out a naive route. At each step, it chooses a coordinate closer to the destination.
-func findNaiveRoute(src, dst common.Coordinate) []common.Coordinate {
+func findNaiveRoute(src, dst common.Coordinate, rnd *rand.Rand) []common.Coordinate {
 	ret := []common.Coordinate{}
 	for c := src; c != dst; {
 		ret = append(ret, c)
-		dir := rand.Intn(2)
+		dir := rnd.Intn(2)
 		if c.X == dst.X {
 			dir = 0
 		}
@@ -420,14 +810,14 @@ func (m *Maze) toRect() rect {
 }
 
 // cuth tries to cut the rect horizontally
-func (r rect) cuth(src, dst common.Coordinate) (rect, common.Coordinate, rect, common.Coordinate, bool) {
+func (r rect) cuth(src, dst common.Coordinate, rnd *rand.Rand) (rect, common.Coordinate, rect, common.Coordinate, bool) {
 	if src.Y == dst.Y || r.H <= cutLimit {
 		return rect{}, common.Coordinate{}, rect{}, common.Coordinate{}, false
 	}
 	cy := (src.Y+dst.Y)/2 + 1
-	cx := rand.Intn(r.W) + r.X
+	cx := rnd.Intn(r.W) + r.X
 	if cx == src.X || cx == dst.X {
-		cx = rand.Intn(r.W) + r.X
+		cx = rnd.Intn(r.W) + r.X
 	}
 	r1 := rect{X: r.X, Y: r.Y, W: r.W, H: cy - r.Y}
 	r2 := rect{X: r.X, Y: cy, W: r.W, H: r.H - r1.H}
@@ -438,14 +828,14 @@ func (r rect) cuth(src, dst common.Coordinate) (rect, common.Coordinate, rect, c
 }
 
 // cutv tries to cut the rect vertically
-func (r rect) cutv(src, dst common.Coordinate) (rect, common.Coordinate, rect, common.Coordinate, bool) {
+func (r rect) cutv(src, dst common.Coordinate, rnd *rand.Rand) (rect, common.Coordinate, rect, common.Coordinate, bool) {
 	if src.X == dst.X || r.W <= cutLimit {
 		return rect{}, common.Coordinate{}, rect{}, common.Coordinate{}, false
 	}
 	cx := (src.X+dst.X)/2 + 1
-	cy := rand.Intn(r.H) + r.Y
+	cy := rnd.Intn(r.H) + r.Y
 	if cy == src.Y || cy == dst.Y {
-		cy = rand.Intn(r.H) + r.Y
+		cy = rnd.Intn(r.H) + r.Y
 	}
 	r1 := rect{X: r.X, Y: r.Y, W: cx - r.X, H: r.H}
 	r2 := rect{X: cx, Y: r.Y, W: r.W - r1.W, H: r.H}
@@ -457,9 +847,9 @@ func (r rect) cutv(src, dst common.Coordinate) (rect, common.Coordinate, rect, c
 
 // cut the rect into two pieces, so src and dst are in different piece, if possible
 // it returns two rects and two neighbouring coordinates, which is the bridge between the two rects.
-func (r rect) cut(src, dst common.Coordinate) (rect, common.Coordinate, rect, common.Coordinate, bool) {
-	hrsrc, hcsrc, hrdst, hcdst, hok := r.cuth(src, dst)
-	vrsrc, vcsrc, vrdst, vcdst, vok := r.cutv(src, dst)
+func (r rect) cut(src, dst common.Coordinate, rnd *rand.Rand) (rect, common.Coordinate, rect, common.Coordinate, bool) {
+	hrsrc, hcsrc, hrdst, hcdst, hok := r.cuth(src, dst, rnd)
+	vrsrc, vcsrc, vrdst, vcdst, vok := r.cutv(src, dst, rnd)
 	if !hok {
 		return vrsrc, vcsrc, vrdst, vcdst, vok
 	}
@@ -473,12 +863,12 @@ func (r rect) cut(src, dst common.Coordinate) (rect, common.Coordinate, rect, co
 }
 
 // findRoute finds a route from source to destination in the sub-maze recursively.
-func (r rect) findRoute(src, dst common.Coordinate) []common.Coordinate {
-	rsrc, csrc, rdst, cdst, ok := r.cut(src, dst)
+func (r rect) findRoute(src, dst common.Coordinate, rnd *rand.Rand) []common.Coordinate {
+	rsrc, csrc, rdst, cdst, ok := r.cut(src, dst, rnd)
 	if !ok {
-		return findNaiveRoute(src, dst)
+		return findNaiveRoute(src, dst, rnd)
 	}
-	return append(rsrc.findRoute(src, csrc), rdst.findRoute(cdst, dst)...)
+	return append(rsrc.findRoute(src, csrc, rnd), rdst.findRoute(cdst, dst, rnd)...)
 }
 
 func (m *Maze) contains(c common.Coordinate) bool {
@@ -530,17 +920,17 @@ func (m *Maze) paveRoute(route []common.Coordinate) {
 
 // floodfill starts from some coordinate, and randomly moves(floodfills) neighboring coordinates if unexplored.
 // This is used to create deadends.
-func (m *Maze) floodfill(c, from common.Coordinate, explored map[common.Coordinate]bool) {
+func (m *Maze) floodfill(c, from common.Coordinate, explored map[common.Coordinate]bool, rnd *rand.Rand) {
 	m.sealRoom(c)
 	m.removeWallBetween(c, from)
 	explored[c] = true
 
 	nbs := c.Neighbors()
-	idxs := rand.Perm(len(nbs))
+	idxs := rnd.Perm(len(nbs))
 	for _, idx := range idxs {
 		nb := nbs[idx]
 		if m.contains(nb) && !explored[nb] {
-			m.floodfill(nb, c, explored)
+			m.floodfill(nb, c, explored, rnd)
 		}
 	}
 }
@@ -548,9 +938,9 @@ func (m *Maze) floodfill(c, from common.Coordinate, explored map[common.Coordina
 // buildMaze is the core algorithm.
 // It first recursively generates a route from source to destination.
 // Then it tries to create deadends from rooms in the route.
-func (m *Maze) buildMaze(src, dst common.Coordinate) {
+func (m *Maze) buildMaze(src, dst common.Coordinate, rnd *rand.Rand) {
 	r := m.toRect()
-	route := r.findRoute(src, dst)
+	route := r.findRoute(src, dst, rnd)
 	m.paveRoute(route)
 
 	explored := make(map[common.Coordinate]bool)
@@ -558,30 +948,70 @@ func (m *Maze) buildMaze(src, dst common.Coordinate) {
 		explored[c] = true
 	}
 
-	order := rand.Perm(len(route) - 1)
+	order := rnd.Perm(len(route) - 1)
 	for _, idx := range order {
 		c := route[idx]
 		for _, nb := range c.Neighbors() {
 			if m.contains(nb) && !explored[nb] {
-				m.floodfill(nb, c, explored)
+				m.floodfill(nb, c, explored, rnd)
 			}
 		}
 	}
 }
 
-func createMaze() *Maze {
+// How many mazes are generated at most, looking for one of the difficulty asked
+const maxDifficultyAttempts = 100
 
+// createMaze builds a maze with the generator chosen by the user,
+// generating again until the maze has the difficulty asked, if any.
+// The same seed always builds the same maze.
+func createMaze(seed int64) (*Maze, error) {
+	g, err := mazelib.GetGenerator(viper.GetString("generator"))
+	if err != nil {
+		return nil, err
+	}
+	rnd := rand.New(rand.NewSource(seed))
+
+	difficulty := viper.GetString("difficulty")
+	if difficulty == "" {
+		return buildWith(g, seed, rnd)
+	}
+	band, err := mazelib.ParseBand(difficulty)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < maxDifficultyAttempts; i++ {
+		m, err := buildWith(g, seed, rnd)
+		if err != nil {
+			return nil, err
+		}
+		d, err := mazelib.Measure(m)
+		if err != nil {
+			return nil, err
+		}
+		if band.Contains(d.Score) {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s laybrinth found in %d attempts", band.Name, maxDifficultyAttempts)
+}
+
+// buildWith builds a maze with the generator, between random start and treasure
+func buildWith(g mazelib.Generator, seed int64, rnd *rand.Rand) (*Maze, error) {
 	m := emptyMaze()
-	sx, sy := rand.Intn(m.Width()), rand.Intn(m.Height())
-	dx, dy := rand.Intn(m.Width()), rand.Intn(m.Height())
+	m.seed = seed
+	sx, sy := rnd.Intn(m.Width()), rnd.Intn(m.Height())
+	dx, dy := rnd.Intn(m.Width()), rnd.Intn(m.Height())
 	for dx == sx && dy == sy {
-		dx, dy = rand.Intn(m.Width()), rand.Intn(m.Height())
+		dx, dy = rnd.Intn(m.Width()), rnd.Intn(m.Height())
 	}
 	m.SetStartPoint(sx, sy)
 	m.SetTreasure(dx, dy)
-	m.addBoundary()
+	m.maxSteps = viper.GetInt("max-steps")
 
-	m.buildMaze(common.NewCoordinate(sx, sy), common.NewCoordinate(dx, dy))
-
-	return m
+	if err := g.Generate(m, m.start, m.end, rnd); err != nil {
+		return nil, err
+	}
+	m.optimal = m.shortestPath()
+	return m, nil
 }
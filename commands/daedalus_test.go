@@ -0,0 +1,133 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/showbufire/gc6/mazelib"
+	"github.com/spf13/viper"
+)
+
+// Every named difficulty must be reachable with the default generator and size,
+// otherwise asking for it makes daedalus fail
+func TestBandsReachable(t *testing.T) {
+	defer viper.Set("difficulty", "")
+	for _, b := range mazelib.Bands {
+		viper.Set("difficulty", b.Name)
+		for seed := int64(1); seed <= 10; seed++ {
+			m, err := createMaze(seed)
+			if err != nil {
+				t.Fatalf("%s maze with seed %d: %v", b.Name, seed, err)
+			}
+			d, err := mazelib.Measure(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !b.Contains(d.Score) {
+				t.Errorf("%s maze with seed %d has score %.2f", b.Name, seed, d.Score)
+			}
+		}
+	}
+}
+
+// corridors is a maze of two corridors going right, joined on the left.
+// Icarus awakes at the top left, the treasure is at the top right.
+//
+//	S . T
+//	. . .
+func corridors(t *testing.T) (*sessionStore, *session) {
+	d := &mazelib.Document{
+		Version:  mazelib.DocumentVersion,
+		Width:    3,
+		Height:   2,
+		Start:    mazelib.Coordinate{0, 0},
+		Treasure: mazelib.Coordinate{2, 0},
+		Rooms: [][]mazelib.Survey{
+			{{Top: true, Left: true}, {Top: true, Bottom: true}, {Top: true, Right: true, Bottom: true}},
+			{{Left: true, Bottom: true}, {Top: true, Bottom: true}, {Top: true, Right: true, Bottom: true}},
+		},
+	}
+	s := newSessionStore(0, false)
+	sess, err := s.create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.maze, err = mazeFromDocument(d); err != nil {
+		t.Fatal(err)
+	}
+	return s, sess
+}
+
+func TestMoves(t *testing.T) {
+	tests := []struct {
+		name       string
+		directions []string
+		status     int
+		victory    bool
+		// surveys of the rooms entered, and where Icarus ends
+		surveys []mazelib.Coordinate
+		icarus  mazelib.Coordinate
+	}{
+		{"wall first", []string{"up", "right"}, http.StatusConflict, false,
+			nil, mazelib.Coordinate{0, 0}},
+		{"wall", []string{"right", "down", "right"}, http.StatusConflict, false,
+			[]mazelib.Coordinate{{1, 0}}, mazelib.Coordinate{1, 0}},
+		{"victory", []string{"right", "right", "left"}, http.StatusOK, true,
+			[]mazelib.Coordinate{{1, 0}, {2, 0}}, mazelib.Coordinate{2, 0}},
+		{"every step", []string{"down", "right", "right"}, http.StatusOK, false,
+			[]mazelib.Coordinate{{0, 1}, {1, 1}, {2, 1}}, mazelib.Coordinate{2, 1}},
+		{"unknown direction", []string{"down", "sideways"}, http.StatusBadRequest, false,
+			nil, mazelib.Coordinate{0, 0}},
+	}
+	for _, tt := range tests {
+		s, sess := corridors(t)
+		r, status := s.moves(sess.id, tt.directions)
+		if status != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, status, tt.status)
+		}
+		if r.Victory != tt.victory {
+			t.Errorf("%s: victory %v, want %v", tt.name, r.Victory, tt.victory)
+		}
+		if len(r.Surveys) != len(tt.surveys) {
+			t.Errorf("%s: %d surveys, want %d", tt.name, len(r.Surveys), len(tt.surveys))
+		} else {
+			for i, c := range tt.surveys {
+				want, _ := sess.maze.Discover(c.X, c.Y)
+				if tt.victory && i == len(tt.surveys)-1 {
+					// daedalus doesn't survey the room of the treasure
+					want = mazelib.Survey{}
+				}
+				if r.Surveys[i] != want {
+					t.Errorf("%s: survey %d is %+v, want %+v", tt.name, i, r.Surveys[i], want)
+				}
+			}
+		}
+		if sess.maze.icarus != tt.icarus {
+			t.Errorf("%s: Icarus is at %v, want %v", tt.name, sess.maze.icarus, tt.icarus)
+		}
+		if sess.maze.StepsTaken != len(tt.surveys) {
+			t.Errorf("%s: %d steps taken, want %d", tt.name, sess.maze.StepsTaken, len(tt.surveys))
+		}
+		// a refused move leaves Icarus seeing the room he is in
+		if status == http.StatusConflict {
+			if want, _ := sess.maze.Discover(tt.icarus.X, tt.icarus.Y); r.Survey != want {
+				t.Errorf("%s: survey %+v, want %+v", tt.name, r.Survey, want)
+			}
+		}
+	}
+}
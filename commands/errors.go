@@ -0,0 +1,85 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/showbufire/gc6/mazelib"
+)
+
+// TransportError is a failure to reach Daedalus at all
+type TransportError struct {
+	Op  string
+	Err error
+	// Unsent tells that Icarus couldn't even connect, so Daedalus never saw the call
+	Unsent bool
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("%s: can't reach daedalus: %v", e.Op, e.Err)
+}
+
+// ProtocolError is a reply from Daedalus which Icarus doesn't expect
+type ProtocolError struct {
+	Op      string
+	Status  int
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%s: daedalus replied %d: %s", e.Op, e.Status, e.Message)
+}
+
+// IllegalMoveError is a move refused by Daedalus, such as walking through a wall
+type IllegalMoveError struct {
+	Direction string
+	Message   string
+}
+
+func (e *IllegalMoveError) Error() string {
+	return fmt.Sprintf("can't move %s: %s", e.Direction, e.Message)
+}
+
+// Exit statuses of icarus
+const (
+	exitTransport   = 2
+	exitProtocol    = 3
+	exitIllegalMove = 4
+)
+
+// exitCode tells the status to exit with after the error
+func exitCode(err error) int {
+	switch err.(type) {
+	case *TransportError:
+		return exitTransport
+	case *ProtocolError:
+		return exitProtocol
+	case *IllegalMoveError:
+		return exitIllegalMove
+	}
+	return -1
+}
+
+// checkReply turns a reply with an unexpected status into a ProtocolError.
+// A move refused with 409 is expected, it's up to the caller to look at the reply.
+func checkReply(op string, r mazelib.Reply, status int) (mazelib.Reply, error) {
+	if status != http.StatusOK && status != http.StatusConflict {
+		return r, &ProtocolError{Op: op, Status: status, Message: r.Message}
+	}
+	return r, nil
+}
@@ -0,0 +1,195 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"sync"
+
+	"github.com/showbufire/gc6/mazelib"
+)
+
+// Types of the events sent to the viewers
+const (
+	eventMaze = "maze"
+	eventMove = "move"
+	eventEnd  = "end"
+)
+
+// How many events a viewer may lag behind, before it's dropped
+const eventBacklog = 256
+
+// event is something that happened in a session, as the viewers see it
+type event struct {
+	Type    string `json:"type"`
+	Session string `json:"session"`
+	// Round counts the mazes of the session
+	Round int `json:"round"`
+	// Maze is sent when Icarus awakes, along with the rooms he went through so far
+	Maze      *mazelib.Document    `json:"maze,omitempty"`
+	Route     []mazelib.Coordinate `json:"route,omitempty"`
+	Icarus    mazelib.Coordinate   `json:"icarus"`
+	Steps     int                  `json:"steps"`
+	Optimal   int                  `json:"optimal,omitempty"`
+	Victory   bool                 `json:"victory,omitempty"`
+	Exhausted bool                 `json:"exhausted,omitempty"`
+}
+
+// eventBus hands the events over to the viewers. It's safe for concurrent use.
+// A viewer too slow to keep up is dropped, rather than slowing Icarus down.
+type eventBus struct {
+	sync.Mutex
+	// subscribers and the session they follow, empty for all of them
+	subscribers map[chan event]string
+	closed      bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[chan event]string)}
+}
+
+// subscribe returns the channel of the events of a session, or of all sessions if id is empty.
+// It returns false once the bus is closed.
+func (b *eventBus) subscribe(id string) (chan event, bool) {
+	b.Lock()
+	defer b.Unlock()
+	if b.closed {
+		return nil, false
+	}
+	ch := make(chan event, eventBacklog)
+	b.subscribers[ch] = id
+	return ch, true
+}
+
+// unsubscribe stops sending events to the channel, unless it's been dropped already
+func (b *eventBus) unsubscribe(ch chan event) {
+	b.Lock()
+	defer b.Unlock()
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// watched tells whether anyone follows the session, so that events aren't made for nobody
+func (b *eventBus) watched(id string) bool {
+	b.Lock()
+	defer b.Unlock()
+	for _, follows := range b.subscribers {
+		if follows == "" || follows == id {
+			return true
+		}
+	}
+	return false
+}
+
+// publish sends the event to the viewers following its session
+func (b *eventBus) publish(e event) {
+	b.Lock()
+	defer b.Unlock()
+	for ch, follows := range b.subscribers {
+		if follows != "" && follows != e.Session {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// close drops all the viewers, and refuses new ones
+func (b *eventBus) close() {
+	b.Lock()
+	defer b.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// stale tells whether a viewer already knows of the event, given the last one it got of the session.
+// Viewers get a snapshot of the sessions first, the events queued meanwhile may be older.
+func (e event) stale(last event) bool {
+	if e.Round != last.Round {
+		return e.Round < last.Round
+	}
+	switch e.Type {
+	case eventMaze:
+		return true
+	case eventMove:
+		return !e.Exhausted && e.Steps <= last.Steps
+	}
+	return false
+}
+
+// mazeEvent describes the maze of a session, and where Icarus went in it.
+// The session must be locked.
+func (sess *session) mazeEvent() (event, error) {
+	d, err := mazelib.NewDocument(sess.maze)
+	if err != nil {
+		return event{}, err
+	}
+	d.Seed = sess.maze.seed
+	return event{
+		Type:    eventMaze,
+		Session: sess.id,
+		Round:   sess.round,
+		Maze:    d,
+		Route:   append([]mazelib.Coordinate{}, sess.route...),
+		Icarus:  sess.maze.icarus,
+		Steps:   sess.maze.StepsTaken,
+		Optimal: sess.maze.optimal,
+	}, nil
+}
+
+// moveEvent tells where Icarus is now. The session must be locked.
+func (sess *session) moveEvent() event {
+	return event{
+		Type:    eventMove,
+		Session: sess.id,
+		Round:   sess.round,
+		Icarus:  sess.maze.icarus,
+		Steps:   sess.maze.StepsTaken,
+		Optimal: sess.maze.optimal,
+	}
+}
+
+// snapshot describes the mazes of the sessions, or of a single one if id isn't empty
+func (s *sessionStore) snapshot(id string) []event {
+	s.Lock()
+	var all []*session
+	for _, sess := range s.sessions {
+		if id == "" || sess.id == id {
+			all = append(all, sess)
+		}
+	}
+	s.Unlock()
+
+	var events []event
+	for _, sess := range all {
+		sess.Lock()
+		if sess.maze != nil {
+			if e, err := sess.mazeEvent(); err == nil {
+				events = append(events, e)
+			}
+		}
+		sess.Unlock()
+	}
+	return events
+}
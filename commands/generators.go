@@ -0,0 +1,222 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/showbufire/gc6/common"
+	"github.com/showbufire/gc6/mazelib"
+)
+
+// defaultGenerator is the original algorithm of daedalus
+const defaultGenerator = "rectcut"
+
+// generator adapts an algorithm working on our own Maze to mazelib.Generator
+type generator func(m *Maze, src, dst common.Coordinate, rnd *rand.Rand)
+
+func (g generator) Generate(m mazelib.MazeI, start, treasure mazelib.Coordinate, rnd *rand.Rand) error {
+	z, ok := m.(*Maze)
+	if !ok {
+		return errors.New("daedalus generators only build daedalus mazes")
+	}
+	g(z, common.Coordinate{start}, common.Coordinate{treasure}, rnd)
+	return nil
+}
+
+func init() {
+	mazelib.RegisterGenerator(defaultGenerator, generator(rectCut))
+}
+
+// rectCut recursively cuts the maze to route from source to destination, then floodfills deadends.
+func rectCut(m *Maze, src, dst common.Coordinate, rnd *rand.Rand) {
+	m.addBoundary()
+	m.buildMaze(src, dst, rnd)
+}
+
+// The generators below carve a perfect maze, a spanning tree of the rooms, out of a full maze.
+// There's exactly one route between any two rooms, so the start and the treasure don't matter.
+
+func init() {
+	mazelib.RegisterGenerator("backtracker", generator(recursiveBacktracker))
+	mazelib.RegisterGenerator("prim", generator(prim))
+	mazelib.RegisterGenerator("kruskal", generator(kruskal))
+	mazelib.RegisterGenerator("wilson", generator(wilson))
+	mazelib.RegisterGenerator("aldous-broder", generator(aldousBroder))
+}
+
+// neighbors returns the neighbors of c inside the maze
+func (m *Maze) neighbors(c common.Coordinate) []common.Coordinate {
+	ret := []common.Coordinate{}
+	for _, nb := range c.Neighbors() {
+		if m.contains(nb) {
+			ret = append(ret, nb)
+		}
+	}
+	return ret
+}
+
+// randomRoom picks any room of the maze
+func (m *Maze) randomRoom(rnd *rand.Rand) common.Coordinate {
+	return common.NewCoordinate(rnd.Intn(m.Width()), rnd.Intn(m.Height()))
+}
+
+// recursiveBacktracker walks randomly to unvisited rooms, backtracking on a stack when stuck.
+// It makes long winding corridors with few deadends.
+func recursiveBacktracker(m *Maze, src, dst common.Coordinate, rnd *rand.Rand) {
+	m.addAllWalls()
+
+	visited := map[common.Coordinate]bool{src: true}
+	stack := []common.Coordinate{src}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		unvisited := []common.Coordinate{}
+		for _, nb := range m.neighbors(c) {
+			if !visited[nb] {
+				unvisited = append(unvisited, nb)
+			}
+		}
+		if len(unvisited) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		nb := unvisited[rnd.Intn(len(unvisited))]
+		m.removeWallBetween(c, nb)
+		visited[nb] = true
+		stack = append(stack, nb)
+	}
+}
+
+// wall is a wall between two neighboring rooms
+type wall struct {
+	c1, c2 common.Coordinate
+}
+
+// prim grows the maze from a random room, knocking down a random wall on its frontier at each step.
+// It makes lots of short deadends.
+func prim(m *Maze, src, dst common.Coordinate, rnd *rand.Rand) {
+	m.addAllWalls()
+
+	start := m.randomRoom(rnd)
+	in := map[common.Coordinate]bool{start: true}
+	frontier := []wall{}
+	for _, nb := range m.neighbors(start) {
+		frontier = append(frontier, wall{start, nb})
+	}
+	for len(frontier) > 0 {
+		i := rnd.Intn(len(frontier))
+		w := frontier[i]
+		frontier[i] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+		if in[w.c2] {
+			continue
+		}
+		m.removeWallBetween(w.c1, w.c2)
+		in[w.c2] = true
+		for _, nb := range m.neighbors(w.c2) {
+			if !in[nb] {
+				frontier = append(frontier, wall{w.c2, nb})
+			}
+		}
+	}
+}
+
+// kruskal knocks down the walls in random order, unless the rooms on both sides are already connected.
+func kruskal(m *Maze, src, dst common.Coordinate, rnd *rand.Rand) {
+	m.addAllWalls()
+
+	walls := []wall{}
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			c := common.NewCoordinate(x, y)
+			if x+1 < m.Width() {
+				walls = append(walls, wall{c, c.Right()})
+			}
+			if y+1 < m.Height() {
+				walls = append(walls, wall{c, c.Down()})
+			}
+		}
+	}
+
+	// a union-find forest over the rooms
+	parent := make(map[common.Coordinate]common.Coordinate)
+	var find func(c common.Coordinate) common.Coordinate
+	find = func(c common.Coordinate) common.Coordinate {
+		p, ok := parent[c]
+		if !ok || p == c {
+			return c
+		}
+		root := find(p)
+		parent[c] = root
+		return root
+	}
+
+	for _, idx := range rnd.Perm(len(walls)) {
+		w := walls[idx]
+		r1, r2 := find(w.c1), find(w.c2)
+		if r1 != r2 {
+			m.removeWallBetween(w.c1, w.c2)
+			parent[r1] = r2
+		}
+	}
+}
+
+// wilson adds loop-erased random walks to the maze until it covers all the rooms.
+// Every spanning tree is equally likely.
+func wilson(m *Maze, src, dst common.Coordinate, rnd *rand.Rand) {
+	m.addAllWalls()
+
+	in := map[common.Coordinate]bool{m.randomRoom(rnd): true}
+	for _, idx := range rnd.Perm(m.Width() * m.Height()) {
+		c := common.NewCoordinate(idx%m.Width(), idx/m.Width())
+		if in[c] {
+			continue
+		}
+		// walk until hitting the maze, remembering the last exit from each room,
+		// which erases the loops
+		exits := make(map[common.Coordinate]common.Coordinate)
+		for cur := c; !in[cur]; {
+			nbs := m.neighbors(cur)
+			next := nbs[rnd.Intn(len(nbs))]
+			exits[cur] = next
+			cur = next
+		}
+		for cur := c; !in[cur]; cur = exits[cur] {
+			m.removeWallBetween(cur, exits[cur])
+			in[cur] = true
+		}
+	}
+}
+
+// aldousBroder walks randomly, knocking down the wall whenever it enters a room for the first time.
+// Every spanning tree is equally likely, but it's slow to finish.
+func aldousBroder(m *Maze, src, dst common.Coordinate, rnd *rand.Rand) {
+	m.addAllWalls()
+
+	c := m.randomRoom(rnd)
+	visited := map[common.Coordinate]bool{c: true}
+	for remaining := m.Width()*m.Height() - 1; remaining > 0; {
+		nbs := m.neighbors(c)
+		nb := nbs[rnd.Intn(len(nbs))]
+		if !visited[nb] {
+			m.removeWallBetween(c, nb)
+			visited[nb] = true
+			remaining--
+		}
+		c = nb
+	}
+}
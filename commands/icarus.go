@@ -16,12 +16,11 @@
 package commands
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
-	"net/http"
+	"os"
+	"time"
 
 	"github.com/showbufire/gc6/mazelib"
 	"github.com/showbufire/gc6/common"
@@ -42,7 +41,10 @@ var icarusCmd = &cobra.Command{
 
   Icarus can connect to a Daedalus and solve many laybrinths at a time.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		RunIcarus()
+		if err := RunIcarus(); err != nil {
+			fmt.Println(err)
+			os.Exit(exitCode(err))
+		}
 	},
 }
 
@@ -57,74 +59,119 @@ var (
 )
 
 func init() {
+	rand.Seed(time.Now().UTC().UnixNano()) // the solver picks its way randomly
 	RootCmd.AddCommand(icarusCmd)
 }
 
-func RunIcarus() {
+// RunIcarus solves the mazes, and tells daedalus when he's done.
+// Daedalus is told even if a maze fails, so that he can stop.
+func RunIcarus() error {
+	return runIcarus(nil)
+}
+
+// entered is told about every room Icarus enters: the solver, the direction he went, zero when he awakes,
+// what he sees and whether he found the treasure. Icarus stops if it returns an error.
+type entered func(solver mazelib.Solver, dir int, survey mazelib.Survey, victory bool) error
+
+// runIcarus is RunIcarus, telling every room entered if it isn't nil
+func runIcarus(enter entered) error {
+	t, err := newTransport()
+	if err != nil {
+		return err
+	}
+
+	if _, err := mazelib.NewSolver(viper.GetString("solver")); err != nil {
+		return err
+	}
+	if err := t.Wait(viper.GetDuration("wait")); err != nil {
+		return err
+	}
+
 	// Run the solver as many times as the user desires.
 	fmt.Println("Solving", viper.GetInt("times"), "times")
 	for x := 0; x < viper.GetInt("times"); x++ {
 		fmt.Printf("Solving %v time\n", x)
-		solveMaze()
+		solver, _ := mazelib.NewSolver(viper.GetString("solver"))
+		if err = solveMaze(t, solver, enter); err != nil {
+			break
+		}
 	}
 
 	// Once we have solved the maze the required times, tell daedalus we are done
-	makeRequest("http://127.0.0.1:" + viper.GetString("port") + "/done")
+	if doneErr := t.Done(); err == nil {
+		err = doneErr
+	}
+	return err
 }
 
 // Make a call to the laybrinth server (daedalus) that icarus is ready to wake up
-func awake() mazelib.Survey {
-	contents, err := makeRequest("http://127.0.0.1:" + viper.GetString("port") + "/awake")
+func awake(t transport) (mazelib.Survey, error) {
+	r, err := t.Awake()
 	if err != nil {
-		fmt.Println(err)
+		return mazelib.Survey{}, err
 	}
-	r := ToReply(contents)
-	return r.Survey
+	fmt.Println("Awake in the laybrinth of seed", r.Seed)
+	return r.Survey, nil
 }
 
 // Make a call to the laybrinth server (daedalus)
 // to move Icarus a given direction
 // Will be used heavily by solveMaze
-func Move(direction string) (mazelib.Survey, error) {
+func Move(t transport, direction string) (mazelib.Survey, error) {
 	if direction == "left" || direction == "right" || direction == "up" || direction == "down" {
 
-		contents, err := makeRequest("http://127.0.0.1:" + viper.GetString("port") + "/move/" + direction)
+		rep, err := t.Move(direction)
 		if err != nil {
 			return mazelib.Survey{}, err
 		}
 
-		rep := ToReply(contents)
+		if rep.Exhausted {
+			return rep.Survey, mazelib.ErrMaxSteps
+		}
+		if rep.Error {
+			return rep.Survey, &IllegalMoveError{Direction: direction, Message: rep.Message}
+		}
 		if rep.Victory == true {
 			fmt.Println(rep.Message)
-			// os.Exit(1)
 			return rep.Survey, mazelib.ErrVictory
 		} else {
 			return rep.Survey, nil
 		}
 	}
 
-	return mazelib.Survey{}, errors.New("invalid direction")
+	return mazelib.Survey{}, &IllegalMoveError{Direction: direction, Message: "invalid direction"}
 }
 
-// utility function to wrap making requests to the daedalus server
-func makeRequest(url string) ([]byte, error) {
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, err
+// Moves makes many moves in a single call, and returns the survey of every room entered.
+// Like Move, it returns ErrVictory or ErrMaxSteps when Icarus stops on the way.
+func Moves(t transport, directions []string) ([]mazelib.Survey, error) {
+	for _, direction := range directions {
+		if !validDirection(direction) {
+			return nil, &IllegalMoveError{Direction: direction, Message: "invalid direction"}
+		}
 	}
-	defer response.Body.Close()
-	contents, err := ioutil.ReadAll(response.Body)
+
+	rep, err := t.Moves(directions)
 	if err != nil {
 		return nil, err
 	}
-	return contents, nil
-}
 
-// Handling a JSON response and unmarshalling it into a reply struct
-func ToReply(in []byte) mazelib.Reply {
-	res := &mazelib.Reply{}
-	json.Unmarshal(in, &res)
-	return *res
+	if rep.Exhausted {
+		return rep.Surveys, mazelib.ErrMaxSteps
+	}
+	if rep.Error {
+		// the move refused is the one after the last room entered
+		direction := directions[len(directions)-1]
+		if len(rep.Surveys) < len(directions) {
+			direction = directions[len(rep.Surveys)]
+		}
+		return rep.Surveys, &IllegalMoveError{Direction: direction, Message: rep.Message}
+	}
+	if rep.Victory {
+		fmt.Println(rep.Message)
+		return rep.Surveys, mazelib.ErrVictory
+	}
+	return rep.Surveys, nil
 }
 
 type Survey struct {
@@ -175,6 +222,11 @@ func (p *path) top() (common.Coordinate, error) {
 	return p.coordinates[p.size-1], nil
 }
 
+// rooms returns the coordinates on the path, from the bottom of the stack
+func (p *path) rooms() []common.Coordinate {
+	return p.coordinates[:p.size]
+}
+
 // backtrack finds something other than the top one that has an explored neighbor
 // warning: it has a side effect on the size
 func (p *path) backtrack(explored map[common.Coordinate]Survey) (common.Coordinate, error) {
@@ -188,42 +240,66 @@ func (p *path) backtrack(explored map[common.Coordinate]Survey) (common.Coordina
 	return common.Coordinate{}, fmt.Errorf("Couldn't find a coordinate, which is not fully explored, in the path")
 }
 
-func solveMaze() {
+// solveMaze lets the solver find the treasure of a new maze, telling every room entered if enter isn't nil.
+// Running out of steps isn't an error, daedalus counts it as a failure.
+func solveMaze(t transport, solver mazelib.Solver, enter entered) error {
 	// Need to start with waking up to initialize a new maze
-	// You'll probably want to set this to a named value and start by figuring
-	// out which step to take next
-
-	explored := make(map[common.Coordinate]Survey)
-	src := common.NewCoordinate(0, 0)
-	explored[src] = Survey{awake()}
-
-	path := newPath()
-	path.push(src)
+	survey, err := awake(t)
+	if err != nil {
+		return err
+	}
+	solver.Start(survey)
+	if enter != nil {
+		if err := enter(solver, 0, survey, false); err != nil {
+			return err
+		}
+	}
 
 	for {
-		icarus, _ := path.top()
-		if next, dir, found := pickNeighbor(icarus, explored); found {
-			survey, err := Move(d2s[dir])
-			if err == mazelib.ErrVictory {
-				return
-			}
-			if err != nil {
-				panic(err)
+		dirs, err := solver.Next()
+		if err != nil {
+			return err
+		}
+		// a way known up front, like the way back, is sent at once
+		var surveys []mazelib.Survey
+		if len(dirs) == 1 {
+			var survey mazelib.Survey
+			if survey, err = Move(t, d2s[dirs[0]]); err != mazelib.ErrMaxSteps {
+				surveys = []mazelib.Survey{survey}
 			}
-			path.push(next)
-			explored[next] = Survey{survey}
 		} else {
-			dst, err := path.backtrack(explored)
-			if err != nil {
-				panic(err)
+			directions := make([]string, len(dirs))
+			for i, dir := range dirs {
+				directions[i] = d2s[dir]
+			}
+			surveys, err = Moves(t, directions)
+		}
+		if err != nil && err != mazelib.ErrVictory && err != mazelib.ErrMaxSteps {
+			return err
+		}
+		for i, survey := range surveys {
+			victory := err == mazelib.ErrVictory && i == len(surveys)-1
+			if !victory {
+				solver.Moved(survey)
+			}
+			if enter != nil {
+				if err := enter(solver, dirs[i], survey, victory); err != nil {
+					return err
+				}
 			}
-			goback(icarus, dst, explored)
+		}
+		if err == mazelib.ErrVictory {
+			return nil
+		}
+		if err == mazelib.ErrMaxSteps {
+			fmt.Println("Icarus gave up after running out of steps")
+			return nil
 		}
 	}
 }
 
-// goback from src to dst by breadth-first searching coordinates already explored
-func goback(src common.Coordinate, dst common.Coordinate, explored map[common.Coordinate]Survey) int {
+// goback finds the way from src to dst by breadth-first searching coordinates already explored
+func goback(src common.Coordinate, dst common.Coordinate, explored map[common.Coordinate]Survey) ([]int, error) {
 	queue := make([]common.Coordinate, len(explored))
 	from := make(map[common.Coordinate]int)
 	queue[0] = dst
@@ -253,14 +329,13 @@ func goback(src common.Coordinate, dst common.Coordinate, explored map[common.Co
 		}
 	}
 	if !found {
-		panic("goback doesn't even find a way back")
+		return nil, errors.New("goback doesn't even find a way back")
 	}
-	ret := 0
+	ret := []int{}
 	for c := src; c != dst; c = c.Neighbor(from[c]) {
-		ret += 1
-		Move(d2s[from[c]])
+		ret = append(ret, from[c])
 	}
-	return ret
+	return ret, nil
 }
 
 // pickNeighbor selects a neighboring unexplored coordinate
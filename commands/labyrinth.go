@@ -20,8 +20,10 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -51,13 +53,40 @@ if there is a wall or not to the top, right, bottom and left. He takes
 one step and then can discover if his new cell has walls on each of
 the four sides.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		go RunServer()
-
-		// give server time to start before sending a request.
-		// There's a better way to do this, but I'm lazy and this is just for fun.
-		time.Sleep(1 * time.Second)
-
-		RunIcarus()
+		// Icarus finds his mazes in memory, there's no need for a server
+		if viper.GetString("transport") == "direct" {
+			if err := RunIcarus(); err != nil {
+				fmt.Println(err)
+				os.Exit(exitCode(err))
+			}
+			return
+		}
+
+		errc := make(chan error, 1)
+		ready := make(chan struct{})
+		go func() {
+			errc <- RunServer(interruptContext(), ready, true)
+		}()
+
+		// wait for the server to listen before sending a request.
+		select {
+		case <-ready:
+		case err := <-errc:
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+
+		icarusErr := RunIcarus()
+
+		// wait for the server to print the results
+		if err := <-errc; err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+		if icarusErr != nil {
+			fmt.Println(icarusErr)
+			os.Exit(exitCode(icarusErr))
+		}
 	},
 }
 
@@ -68,17 +97,58 @@ func init() {
 	// by the indidual behaviors of icarus and daedalus
 	RootCmd.PersistentFlags().StringVar(&CfgFile, "config", "", "config file (default is $CWD/config.yaml)")
 	RootCmd.PersistentFlags().IntP("port", "p", 8013, "Port run on")
+	RootCmd.PersistentFlags().StringP("server", "s", "", "URL of the daedalus icarus connects to, with an optional base path (default is http://127.0.0.1:<port>)")
+	RootCmd.PersistentFlags().String("transport", "http", "How icarus reaches daedalus: http, websocket to make every call on one connection, or direct to solve the laybrinths in memory")
+	RootCmd.PersistentFlags().Duration("wait", 10*time.Second, "How long icarus waits for daedalus to be ready")
+	RootCmd.PersistentFlags().Int("retries", 3, "How many times icarus retries a call that couldn't connect to daedalus")
 	RootCmd.PersistentFlags().IntP("width", "x", 15, "width of the laybrinth")
 	RootCmd.PersistentFlags().IntP("height", "y", 10, "height of the laybrinth") // 'h' is used for help already
 	RootCmd.PersistentFlags().IntP("times", "t", 1, "times to solve the laybrinth")
 	RootCmd.PersistentFlags().IntP("max-steps", "m", 500, "Maximum steps before giving up")
+	RootCmd.PersistentFlags().StringP("generator", "g", defaultGenerator, "Algorithm used to generate the laybrinth")
+	RootCmd.PersistentFlags().String("difficulty", "", "Generate laybrinths until one is easy, medium, hard, or in a range of scores like 0.9-1.1 (default is any)")
+	RootCmd.PersistentFlags().String("solver", defaultSolver, "Algorithm used to solve the laybrinth")
+	RootCmd.PersistentFlags().Int64("seed", 0, "Seed of the first laybrinth, the following ones count up from it (default is random)")
+	RootCmd.PersistentFlags().String("maze-dir", "", "Serve the laybrinths saved in this directory instead of generating them")
+	RootCmd.PersistentFlags().String("save-dir", "", "Save every generated laybrinth in this directory")
+	RootCmd.PersistentFlags().String("record-dir", "", "Save the moves Icarus makes in every laybrinth in this directory, to replay them with render --moves")
+	RootCmd.PersistentFlags().String("results-format", "table", "How daedalus prints the results: table or json")
+	RootCmd.PersistentFlags().String("results-file", "", "Write the overall results to this file instead of stdout")
+	RootCmd.PersistentFlags().Duration("session-timeout", 5*time.Minute, "Idle time before a session expires")
 
 	// Bind viper to these flags so viper can read flag values along with config, env, etc.
 	viper.BindPFlag("width", RootCmd.PersistentFlags().Lookup("width"))
 	viper.BindPFlag("height", RootCmd.PersistentFlags().Lookup("height"))
 	viper.BindPFlag("port", RootCmd.PersistentFlags().Lookup("port"))
+	viper.BindPFlag("server", RootCmd.PersistentFlags().Lookup("server"))
+	viper.BindPFlag("transport", RootCmd.PersistentFlags().Lookup("transport"))
+	viper.BindPFlag("wait", RootCmd.PersistentFlags().Lookup("wait"))
+	viper.BindPFlag("retries", RootCmd.PersistentFlags().Lookup("retries"))
 	viper.BindPFlag("times", RootCmd.PersistentFlags().Lookup("times"))
 	viper.BindPFlag("max-steps", RootCmd.PersistentFlags().Lookup("max-steps"))
+	viper.BindPFlag("generator", RootCmd.PersistentFlags().Lookup("generator"))
+	viper.BindPFlag("difficulty", RootCmd.PersistentFlags().Lookup("difficulty"))
+	viper.BindPFlag("solver", RootCmd.PersistentFlags().Lookup("solver"))
+	viper.BindPFlag("seed", RootCmd.PersistentFlags().Lookup("seed"))
+	viper.BindPFlag("maze-dir", RootCmd.PersistentFlags().Lookup("maze-dir"))
+	viper.BindPFlag("save-dir", RootCmd.PersistentFlags().Lookup("save-dir"))
+	viper.BindPFlag("record-dir", RootCmd.PersistentFlags().Lookup("record-dir"))
+	viper.BindPFlag("results-format", RootCmd.PersistentFlags().Lookup("results-format"))
+	viper.BindPFlag("results-file", RootCmd.PersistentFlags().Lookup("results-file"))
+	viper.BindPFlag("session-timeout", RootCmd.PersistentFlags().Lookup("session-timeout"))
+}
+
+// interruptContext returns a context canceled when ctrl+c is pressed
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		signal.Stop(c)
+		cancel()
+	}()
+	return ctx
 }
 
 // Read in config file and ENV variables if set.
@@ -0,0 +1,131 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/showbufire/gc6/mazelib"
+)
+
+// mazeFromDocument builds a maze with the rooms of the document, Icarus is at the start
+func mazeFromDocument(d *mazelib.Document) (*Maze, error) {
+	if err := d.Check(); err != nil {
+		return nil, err
+	}
+	m := &Maze{seed: d.Seed}
+	m.rooms = make([][]mazelib.Room, d.Height)
+	for y := 0; y < d.Height; y++ {
+		m.rooms[y] = make([]mazelib.Room, d.Width)
+		for x := 0; x < d.Width; x++ {
+			m.rooms[y][x].Walls = d.Rooms[y][x]
+		}
+	}
+	if err := m.SetStartPoint(d.Start.X, d.Start.Y); err != nil {
+		return nil, err
+	}
+	if err := m.SetTreasure(d.Treasure.X, d.Treasure.Y); err != nil {
+		return nil, err
+	}
+	m.optimal = m.shortestPath()
+	return m, nil
+}
+
+// loadMaze reads a maze from a JSON file, or a text file drawn like mazelib.PrintMaze does
+func loadMaze(path string) (*Maze, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	read := mazelib.ReadDocument
+	if filepath.Ext(path) == ".txt" {
+		read = mazelib.ParseASCII
+	}
+	d, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return mazeFromDocument(d)
+}
+
+// saveMaze writes a maze to a JSON file
+func saveMaze(path string, m *Maze) error {
+	d, err := mazelib.NewDocument(m)
+	if err != nil {
+		return err
+	}
+	d.Seed = m.seed
+
+	var buf bytes.Buffer
+	if err := mazelib.WriteDocument(&buf, d); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// moveRecord is the list of the moves Icarus made in a maze, saved to replay them
+type moveRecord struct {
+	// Seed of the maze, zero if unknown
+	Seed  int64    `json:"seed,omitempty"`
+	Moves []string `json:"moves"`
+}
+
+// saveMoves writes the moves to a JSON file
+func saveMoves(path string, r moveRecord) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// loadMoves reads the moves saved by saveMoves
+func loadMoves(path string) (moveRecord, error) {
+	var r moveRecord
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return r, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// mazeFiles lists the JSON and text files of a directory, in name order
+func mazeFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.txt"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no maze found in %s", dir)
+	}
+	sort.Strings(files)
+	return files, nil
+}
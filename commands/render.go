@@ -0,0 +1,370 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/showbufire/gc6/mazelib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Defining the render command.
+// This will be called as 'laybrinth render <file>'
+var renderCmd = &cobra.Command{
+	Use:   "render <file>",
+	Short: "Draw a saved laybrinth as an image",
+	Long: `Render reads a laybrinth saved as JSON, or drawn in a .txt file the way
+  daedalus prints it, and draws it as an image with the start and the
+  treasure marked. SVG, PNG and GIF images can be styled.
+
+  GIF images replay the way Icarus goes, a frame per move, revealing the
+  rooms as he enters them. Give --moves a file daedalus saved with
+  --record-dir to replay a run, else the laybrinth is solved in memory.
+
+  Text is drawn with box-drawing characters, in colors on a terminal,
+  and written to the standard output unless --output is given.
+
+  It can also draw the way Icarus went and the rooms he entered, in a
+  recorded run or solving the laybrinth in memory with the --solver,
+  or draw the shortest way from the start to the treasure.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println("render takes exactly one file")
+			os.Exit(-1)
+		}
+		opts, err := renderFlags(cmd)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+		if err := renderMaze(args[0], opts); err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	renderCmd.Flags().String("format", "svg", "image format: "+strings.Join(renderFormats(), ", "))
+	renderCmd.Flags().StringP("output", "o", "", "file to write, - for the standard output (default is the laybrinth file, with the extension of the format)")
+	renderCmd.Flags().Bool("landmarks", true, "mark the start and the treasure")
+	renderCmd.Flags().Bool("trajectory", false, "draw the way the solver goes")
+	renderCmd.Flags().Bool("solution", false, "draw the shortest way from the start to the treasure")
+	renderCmd.Flags().Bool("visited", false, "shade the rooms the solver enters, the more visits the darker")
+	renderCmd.Flags().String("moves", "", "replay the moves recorded in this file instead of solving the laybrinth, implies --trajectory")
+	renderCmd.Flags().Int("cell", mazelib.DefaultStyle.Cell, "size of a room in pixels")
+	renderCmd.Flags().Int("wall", mazelib.DefaultStyle.Wall, "thickness of the walls in pixels")
+	renderCmd.Flags().String("background", hexColor(mazelib.DefaultStyle.Background), "color of the rooms")
+	renderCmd.Flags().String("wall-color", hexColor(mazelib.DefaultStyle.Walls), "color of the walls")
+	renderCmd.Flags().String("trajectory-color", hexColor(mazelib.DefaultStyle.Trajectory), "color of the trajectory")
+	renderCmd.Flags().String("fog-color", hexColor(mazelib.DefaultStyle.Fog), "color of the rooms Icarus hasn't entered yet, in replays")
+	renderCmd.Flags().Int("delay", 10, "time between the frames of replays, in 100ths of a second")
+	renderCmd.Flags().String("heat-color", hexColor(mazelib.DefaultStyle.Heat), "color of the most visited rooms")
+	renderCmd.Flags().String("color", "auto", "color the text: auto when writing to a terminal, always or never")
+	RootCmd.AddCommand(renderCmd)
+}
+
+// renderOptions are what the user asks to render, and how
+type renderOptions struct {
+	format string
+	output string
+	style  mazelib.Style
+	// delay between the frames of GIF replays, in 100ths of a second
+	delay int
+	// color is auto, always or never
+	color string
+	// moves is the file of the recorded moves to replay, if any
+	moves string
+
+	landmarks  bool
+	trajectory bool
+	solution   bool
+	visited    bool
+}
+
+// renderFlags reads the render options from the flags
+func renderFlags(cmd *cobra.Command) (*renderOptions, error) {
+	opts := &renderOptions{}
+	opts.format, _ = cmd.Flags().GetString("format")
+	opts.output, _ = cmd.Flags().GetString("output")
+	opts.delay, _ = cmd.Flags().GetInt("delay")
+	opts.color, _ = cmd.Flags().GetString("color")
+	opts.moves, _ = cmd.Flags().GetString("moves")
+	opts.landmarks, _ = cmd.Flags().GetBool("landmarks")
+	opts.trajectory, _ = cmd.Flags().GetBool("trajectory")
+	opts.solution, _ = cmd.Flags().GetBool("solution")
+	opts.visited, _ = cmd.Flags().GetBool("visited")
+
+	if opts.trajectory && opts.solution {
+		return nil, errors.New("--trajectory and --solution draw the same line, pick one")
+	}
+	// there's nothing to replay without the trajectory
+	if opts.format == "gif" && !opts.solution {
+		opts.trajectory = true
+	}
+	if opts.moves != "" && !opts.solution && !opts.visited {
+		opts.trajectory = true
+	}
+	if opts.format == "text" && opts.output == "" {
+		opts.output = "-"
+	}
+	switch opts.color {
+	case "auto", "always", "never":
+	default:
+		return nil, fmt.Errorf("unknown color %q, expecting auto, always or never", opts.color)
+	}
+
+	// text has the colors of the terminal, and a character per room
+	if opts.format == "text" {
+		for _, flag := range styleFlags {
+			if cmd.Flags().Changed(flag) {
+				return nil, fmt.Errorf("--%s can't be used with the text format", flag)
+			}
+		}
+	}
+
+	var err error
+	opts.style, err = renderStyle(cmd)
+	return opts, err
+}
+
+// styleFlags are the flags of the style of the images
+var styleFlags = []string{"cell", "wall", "background", "wall-color", "trajectory-color", "fog-color", "heat-color"}
+
+// renderer draws a maze in some format
+type renderer func(w io.Writer, m mazelib.MazeI, o *mazelib.Overlay) error
+
+// renderers make the renderer of each format, with the options the format has
+var renderers = map[string]func(opts *renderOptions, terminal bool) renderer{
+	"svg": func(opts *renderOptions, _ bool) renderer {
+		return func(w io.Writer, m mazelib.MazeI, o *mazelib.Overlay) error {
+			return mazelib.WriteSVG(w, m, o, opts.style)
+		}
+	},
+	"png": func(opts *renderOptions, _ bool) renderer {
+		return func(w io.Writer, m mazelib.MazeI, o *mazelib.Overlay) error {
+			return mazelib.WritePNG(w, m, o, opts.style)
+		}
+	},
+	"gif": func(opts *renderOptions, _ bool) renderer {
+		return func(w io.Writer, m mazelib.MazeI, o *mazelib.Overlay) error {
+			return mazelib.WriteGIF(w, m, o, opts.style, opts.delay)
+		}
+	},
+	"text": func(opts *renderOptions, terminal bool) renderer {
+		color := opts.color == "always" || opts.color == "auto" && terminal
+		return func(w io.Writer, m mazelib.MazeI, o *mazelib.Overlay) error {
+			return mazelib.WriteText(w, m, o, color)
+		}
+	},
+}
+
+func renderFormats() []string {
+	formats := []string{}
+	for f := range renderers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// renderMaze draws a saved maze, solving it first if the overlay needs it
+func renderMaze(path string, opts *renderOptions) error {
+	newRenderer, ok := renderers[opts.format]
+	if !ok {
+		return fmt.Errorf("unknown format %q (available: %s)", opts.format, strings.Join(renderFormats(), ", "))
+	}
+	m, err := loadMaze(path)
+	if err != nil {
+		return err
+	}
+
+	o := &mazelib.Overlay{Start: opts.landmarks, Treasure: opts.landmarks}
+	if opts.trajectory || opts.visited {
+		route, err := walk(m, opts)
+		if err != nil {
+			return err
+		}
+		// draw the maze as it was before Icarus went through it
+		if m, err = loadMaze(path); err != nil {
+			return err
+		}
+		if opts.trajectory {
+			o.Trajectory = route
+		}
+		if opts.visited {
+			o.Visits = mazelib.CountVisits(route)
+		}
+	}
+	if opts.solution {
+		if o.Trajectory, err = mazelib.ShortestPath(m, m.start, m.end); err != nil {
+			return err
+		}
+	}
+
+	output := opts.output
+	if output == "" {
+		output = strings.TrimSuffix(path, filepath.Ext(path)) + "." + opts.format
+	}
+	if output == "-" {
+		return newRenderer(opts, isTerminal(os.Stdout))(os.Stdout, m, o)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := newRenderer(opts, false)(f, m, o); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println("Rendered", path, "to", output)
+	return nil
+}
+
+// walk takes Icarus through the maze, replaying the moves recorded if there are some,
+// and returns the rooms he went through
+func walk(m *Maze, opts *renderOptions) ([]mazelib.Coordinate, error) {
+	if opts.moves != "" {
+		r, err := loadMoves(opts.moves)
+		if err != nil {
+			return nil, err
+		}
+		return replay(m, r)
+	}
+	solver, err := mazelib.NewSolver(viper.GetString("solver"))
+	if err != nil {
+		return nil, err
+	}
+	return trace(m, solver, nil)
+}
+
+// replay takes Icarus through the recorded moves, and returns the rooms he went through
+func replay(m *Maze, r moveRecord) ([]mazelib.Coordinate, error) {
+	if r.Seed != 0 && m.seed != 0 && r.Seed != m.seed {
+		return nil, fmt.Errorf("the moves were made in the laybrinth with seed %d, not %d", r.Seed, m.seed)
+	}
+	route := []mazelib.Coordinate{m.icarus}
+	for i, dir := range r.Moves {
+		if err := m.move(dir); err != nil {
+			return nil, fmt.Errorf("move %d, %s: %v", i+1, dir, err)
+		}
+		route = append(route, m.icarus)
+	}
+	return route, nil
+}
+
+// isTerminal tells whether the file is a terminal, rather than a pipe, a file or a device like /dev/null
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd())
+}
+
+// renderStyle reads the style of the images from the flags
+func renderStyle(cmd *cobra.Command) (mazelib.Style, error) {
+	s := mazelib.DefaultStyle
+	s.Cell, _ = cmd.Flags().GetInt("cell")
+	s.Wall, _ = cmd.Flags().GetInt("wall")
+	for flag, c := range map[string]*color.RGBA{
+		"background":       &s.Background,
+		"wall-color":       &s.Walls,
+		"trajectory-color": &s.Trajectory,
+		"heat-color":       &s.Heat,
+		"fog-color":        &s.Fog,
+	} {
+		v, _ := cmd.Flags().GetString(flag)
+		var err error
+		if *c, err = parseColor(v); err != nil {
+			return s, fmt.Errorf("--%s: %v", flag, err)
+		}
+	}
+	return s, nil
+}
+
+// parseColor reads a color written as #rrggbb
+func parseColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("bad color %q, expecting #rrggbb", s)
+	}
+	n, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("bad color %q, expecting #rrggbb", s)
+	}
+	return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 0xff}, nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// trace solves the maze in memory with the solver, and returns the rooms Icarus went through.
+// Icarus stops when he finds the treasure, or runs out of steps.
+// If moved isn't nil, it's called with the rooms so far when Icarus awakes and after every move,
+// and the solve stops if it returns an error.
+func trace(m *Maze, solver mazelib.Solver, moved func(route []mazelib.Coordinate) error) ([]mazelib.Coordinate, error) {
+	m.maxSteps = viper.GetInt("max-steps")
+	route := []mazelib.Coordinate{m.icarus}
+	survey, err := m.LookAround()
+	if err != nil {
+		return nil, err
+	}
+	solver.Start(survey)
+	if moved != nil {
+		if err := moved(route); err != nil {
+			return route, err
+		}
+	}
+
+	for {
+		dirs, err := solver.Next()
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			if err := m.move(d2s[dir]); err == mazelib.ErrMaxSteps {
+				return route, nil
+			} else if err != nil {
+				return nil, err
+			}
+			route = append(route, m.icarus)
+			if moved != nil {
+				if err := moved(route); err != nil {
+					return route, err
+				}
+			}
+			survey, err := m.LookAround()
+			if err == mazelib.ErrVictory {
+				return route, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			solver.Moved(survey)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/showbufire/gc6/mazelib"
+	"github.com/showbufire/gc6/stats"
+)
+
+// session is the state of a single Icarus talking to the server.
+// The embedded mutex guards the maze, so that one client can't race itself.
+type session struct {
+	sync.Mutex
+	id       string
+	maze     *Maze
+	scores   []stats.Run
+	failures int
+	// failed is set once Icarus runs out of steps in the current maze
+	failed   bool
+	lastSeen time.Time
+	// round counts the mazes, route are the rooms Icarus went through in the current one
+	round int
+	route []mazelib.Coordinate
+	// moves are the directions Icarus took in the current maze, recorded once he's done with it
+	moves    []string
+	recorded bool
+}
+
+// sessionStore keeps track of all the sessions. It's safe for concurrent use.
+type sessionStore struct {
+	sync.Mutex
+	sessions map[string]*session
+	scores   []stats.Run
+	failures int
+	timeout  time.Duration
+	// untilDone tells to finish once the last session is done, rather than wait for a signal
+	untilDone bool
+	// finished is closed once the last session is done, or the server stops
+	finished   chan struct{}
+	finishOnce sync.Once
+	// events are sent to the viewers
+	events *eventBus
+}
+
+func newSessionStore(timeout time.Duration, untilDone bool) *sessionStore {
+	return &sessionStore{
+		sessions:  make(map[string]*session),
+		timeout:   timeout,
+		untilDone: untilDone,
+		finished:  make(chan struct{}),
+		events:    newEventBus(),
+	}
+}
+
+// newSessionID returns a random hex string, hard enough to guess.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// create starts a new session
+func (s *sessionStore) create() (*session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	sess := &session{id: id, lastSeen: time.Now()}
+
+	s.Lock()
+	defer s.Unlock()
+	s.sessions[id] = sess
+	return sess, nil
+}
+
+// get looks up a session, and marks it as active
+func (s *sessionStore) get(id string) (*session, bool) {
+	s.Lock()
+	defer s.Unlock()
+	sess, ok := s.sessions[id]
+	if ok {
+		sess.lastSeen = time.Now()
+	}
+	return sess, ok
+}
+
+// remove ends a session, and returns how many sessions are left
+func (s *sessionStore) remove(id string) int {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.sessions, id)
+	return len(s.sessions)
+}
+
+// record adds a score to both the session and the overall results
+func (s *sessionStore) record(sess *session, run stats.Run) {
+	sess.scores = append(sess.scores, run)
+
+	s.Lock()
+	defer s.Unlock()
+	s.scores = append(s.scores, run)
+}
+
+// fail counts a maze in which Icarus ran out of steps, separately from the scores
+func (s *sessionStore) fail(sess *session) {
+	sess.failed = true
+	sess.failures++
+
+	s.Lock()
+	defer s.Unlock()
+	s.failures++
+}
+
+// results returns a copy of the overall scores, and the number of failures
+func (s *sessionStore) results() ([]stats.Run, int) {
+	s.Lock()
+	defer s.Unlock()
+	return append([]stats.Run{}, s.scores...), s.failures
+}
+
+// expire removes the sessions which have been idle for longer than the timeout, as if they were done
+func (s *sessionStore) expire(now time.Time) {
+	s.Lock()
+	var expired []*session
+	for id, sess := range s.sessions {
+		if now.Sub(sess.lastSeen) > s.timeout {
+			delete(s.sessions, id)
+			expired = append(expired, sess)
+		}
+	}
+	left := len(s.sessions)
+	s.Unlock()
+
+	// like a session done, save what Icarus did and stop once the last one is gone
+	for _, sess := range expired {
+		sess.Lock()
+		s.recordMoves(sess)
+		s.events.publish(event{Type: eventEnd, Session: sess.id, Round: sess.round})
+		sess.Unlock()
+	}
+	if len(expired) > 0 && left == 0 && s.untilDone {
+		s.finish()
+	}
+}
+
+// finish tells that no more sessions are expected. It's safe to call more than once.
+func (s *sessionStore) finish() {
+	s.finishOnce.Do(func() {
+		close(s.finished)
+	})
+}
+
+// reap expires idle sessions periodically, until the store is finished
+func (s *sessionStore) reap() {
+	if s.timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.expire(now)
+		case <-s.finished:
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestExpireFinishes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "moves")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	viper.Set("record-dir", dir)
+	defer viper.Set("record-dir", "")
+
+	s := newSessionStore(time.Minute, true)
+	sess, err := s.create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.maze, err = createMaze(1); err != nil {
+		t.Fatal(err)
+	}
+
+	s.expire(time.Now())
+	select {
+	case <-s.finished:
+		t.Fatal("the store finished while the session was active")
+	default:
+	}
+
+	s.expire(time.Now().Add(2 * time.Minute))
+	select {
+	case <-s.finished:
+	default:
+		t.Fatal("the store didn't finish once the last session expired")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.moves")); len(files) != 1 {
+		t.Errorf("recorded %v, want the moves of the expired session", files)
+	}
+}
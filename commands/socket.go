@@ -0,0 +1,198 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/showbufire/gc6/mazelib"
+	"golang.org/x/net/websocket"
+)
+
+// Calls Icarus makes over a WebSocket
+const (
+	opAwake = "awake"
+	opMove  = "move"
+	opMoves = "moves"
+	opDone  = "done"
+)
+
+// socketRequest is a call of Icarus over a WebSocket
+type socketRequest struct {
+	Op string `json:"op"`
+	// Direction of a move: left, right, up or down
+	Direction string `json:"direction,omitempty"`
+	// Directions of many moves at once
+	Directions []string `json:"directions,omitempty"`
+	// Seed asked for on awake, to replay a maze
+	Seed string `json:"seed,omitempty"`
+}
+
+// socketReply is the reply to a call, with the HTTP status the call would have had
+type socketReply struct {
+	mazelib.Reply
+	Status int `json:"status"`
+}
+
+// The API response to the /ws address.
+// Icarus makes his calls one after the other on a single connection, which keeps his session.
+// A connection can take over a session given as ?session=<id>, to carry on after a failure.
+// The connection is closed once Icarus is done.
+func MoveSocket(ws *websocket.Conn) {
+	defer ws.Close()
+	id := ws.Request().URL.Query().Get("session")
+	for {
+		var req socketRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			return
+		}
+
+		var r mazelib.Reply
+		var status int
+		last := false
+		switch req.Op {
+		case opAwake:
+			if r, status = sessions.awake(id, req.Seed); status == http.StatusOK {
+				id = r.Session
+			}
+		case opMove:
+			r, status = sessions.move(id, req.Direction)
+		case opMoves:
+			r, status = sessions.moves(id, req.Directions)
+		case opDone:
+			r, status, last = sessions.end(id)
+		default:
+			r, status = mazelib.Reply{Error: true, Message: "unknown call " + req.Op}, http.StatusBadRequest
+		}
+
+		if err := websocket.JSON.Send(ws, socketReply{r, status}); err != nil {
+			return
+		}
+		if req.Op == opDone && status == http.StatusOK {
+			if last {
+				sessions.finish()
+			}
+			return
+		}
+	}
+}
+
+// socketTransport talks to a Daedalus server over a single WebSocket
+type socketTransport struct {
+	// http waits for daedalus to be ready
+	http *httpTransport
+	conn *websocket.Conn
+	// session given by daedalus on the first awake
+	session string
+	// times to retry connecting for a call
+	retries int
+}
+
+// socketURL is the address of the WebSocket under the base path of the server,
+// carrying the session if there is one
+func (t *socketTransport) socketURL() string {
+	u := *t.http.base
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws"
+	u.RawQuery = ""
+	if t.session != "" {
+		u.RawQuery = url.Values{"session": {t.session}}.Encode()
+	}
+	return u.String()
+}
+
+// Wait polls /healthz until it answers, then connects
+func (t *socketTransport) Wait(timeout time.Duration) error {
+	if err := t.http.Wait(timeout); err != nil {
+		return err
+	}
+	if err := t.dial(); err != nil {
+		return &TransportError{Op: "wait", Err: err}
+	}
+	return nil
+}
+
+func (t *socketTransport) dial() error {
+	conn, err := websocket.Dial(t.socketURL(), "", t.http.base.String())
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	return nil
+}
+
+func (t *socketTransport) Awake() (mazelib.Reply, error) {
+	r, err := t.call(socketRequest{Op: opAwake})
+	if err != nil {
+		return r, err
+	}
+	t.session = r.Session
+	return r, nil
+}
+
+func (t *socketTransport) Move(direction string) (mazelib.Reply, error) {
+	return t.call(socketRequest{Op: opMove, Direction: direction})
+}
+
+func (t *socketTransport) Moves(directions []string) (mazelib.Reply, error) {
+	return t.call(socketRequest{Op: opMoves, Directions: directions})
+}
+
+func (t *socketTransport) Done() error {
+	_, err := t.call(socketRequest{Op: opDone})
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+	return err
+}
+
+// call sends a request to daedalus and decodes the reply.
+// A broken connection is dropped, the next call makes a new one.
+func (t *socketTransport) call(req socketRequest) (mazelib.Reply, error) {
+	if t.conn == nil {
+		// nothing is sent until the connection is made, it can be tried again
+		err := retry(t.retries, func() error {
+			if err := t.dial(); err != nil {
+				return &TransportError{Op: req.Op, Err: err, Unsent: true}
+			}
+			return nil
+		})
+		if err != nil {
+			return mazelib.Reply{}, err
+		}
+	}
+	var contents []byte
+	err := websocket.JSON.Send(t.conn, req)
+	if err == nil {
+		err = websocket.Message.Receive(t.conn, &contents)
+	}
+	if err != nil {
+		t.conn.Close()
+		t.conn = nil
+		return mazelib.Reply{}, &TransportError{Op: req.Op, Err: err}
+	}
+
+	var r socketReply
+	if err := json.Unmarshal(contents, &r); err != nil {
+		return mazelib.Reply{}, &ProtocolError{Op: req.Op, Message: err.Error()}
+	}
+	return checkReply(req.Op, r.Reply, r.Status)
+}
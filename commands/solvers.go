@@ -0,0 +1,131 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"errors"
+
+	"github.com/showbufire/gc6/common"
+	"github.com/showbufire/gc6/mazelib"
+)
+
+// defaultSolver is the original algorithm of icarus
+const defaultSolver = "dfs"
+
+func init() {
+	mazelib.RegisterSolver(defaultSolver, func() mazelib.Solver { return &dfsSolver{} })
+	mazelib.RegisterSolver("wallfollower", func() mazelib.Solver { return &wallFollower{} })
+}
+
+// dfsSolver explores randomly in depth first order.
+// When stuck, it goes back through the explored rooms to the latest room on its path
+// which still has an unexplored neighbor.
+// Coordinates are relative to where Icarus awoke.
+type dfsSolver struct {
+	explored map[common.Coordinate]Survey
+	path     *path
+	icarus   common.Coordinate
+	// rooms the moves returned by Next lead to
+	pending []common.Coordinate
+}
+
+func (d *dfsSolver) Start(s mazelib.Survey) {
+	d.icarus = common.NewCoordinate(0, 0)
+	d.explored = map[common.Coordinate]Survey{d.icarus: Survey{s}}
+	d.path = newPath()
+	d.path.push(d.icarus)
+	d.pending = nil
+}
+
+func (d *dfsSolver) Next() ([]int, error) {
+	if next, dir, found := pickNeighbor(d.icarus, d.explored); found {
+		d.pending = []common.Coordinate{next}
+		return []int{dir}, nil
+	}
+	dst, err := d.path.backtrack(d.explored)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := goback(d.icarus, dst, d.explored)
+	if err != nil {
+		return nil, err
+	}
+	d.pending = d.pending[:0]
+	for c, i := d.icarus, 0; i < len(dirs); i++ {
+		c = c.Neighbor(dirs[i])
+		d.pending = append(d.pending, c)
+	}
+	return dirs, nil
+}
+
+func (d *dfsSolver) Moved(s mazelib.Survey) {
+	d.icarus, d.pending = d.pending[0], d.pending[1:]
+	if _, ok := d.explored[d.icarus]; !ok {
+		d.explored[d.icarus] = Survey{s}
+		d.path.push(d.icarus)
+	}
+}
+
+// Stack returns the rooms the solver may backtrack to, from where Icarus awoke
+func (d *dfsSolver) Stack() []mazelib.Coordinate {
+	var rooms []mazelib.Coordinate
+	for _, c := range d.path.rooms() {
+		rooms = append(rooms, c.Coordinate)
+	}
+	return rooms
+}
+
+// wallFollower keeps its left hand on the wall.
+// It finds the treasure in any perfect maze, but may go around in circles in other ones.
+type wallFollower struct {
+	survey  Survey
+	heading int
+}
+
+// leftOf and rightOf turn a heading
+var (
+	leftOf = map[int]int{
+		mazelib.N: mazelib.W,
+		mazelib.W: mazelib.S,
+		mazelib.S: mazelib.E,
+		mazelib.E: mazelib.N,
+	}
+	rightOf = map[int]int{
+		mazelib.N: mazelib.E,
+		mazelib.E: mazelib.S,
+		mazelib.S: mazelib.W,
+		mazelib.W: mazelib.N,
+	}
+)
+
+func (w *wallFollower) Start(s mazelib.Survey) {
+	w.survey = Survey{s}
+	w.heading = mazelib.N
+}
+
+func (w *wallFollower) Next() ([]int, error) {
+	for _, dir := range []int{leftOf[w.heading], w.heading, rightOf[w.heading], common.ReverseDirection[w.heading]} {
+		if !w.survey.HasWall(dir) {
+			w.heading = dir
+			return []int{dir}, nil
+		}
+	}
+	return nil, errors.New("Icarus is walled in")
+}
+
+func (w *wallFollower) Moved(s mazelib.Survey) {
+	w.survey = Survey{s}
+}
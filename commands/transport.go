@@ -0,0 +1,314 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/showbufire/gc6/mazelib"
+	"github.com/spf13/viper"
+)
+
+// transport carries the calls of Icarus to Daedalus.
+// A call is retried, up to --retries times, only when Icarus couldn't connect to Daedalus,
+// so that Daedalus never saw it. Once sent, a call is never repeated even if the reply is lost:
+// a second awake would leave a session behind, a move could be taken twice,
+// and Daedalus may have ended the session, or even stopped, after done.
+type transport interface {
+	// Wait returns once Daedalus is ready, or fails after the timeout
+	Wait(timeout time.Duration) error
+	// Awake asks for a new maze
+	Awake() (mazelib.Reply, error)
+	// Move takes Icarus one step: left, right, up or down
+	Move(direction string) (mazelib.Reply, error)
+	// Moves takes Icarus through the directions, until a move is refused or he finds the treasure.
+	// The reply has the survey of every room he entered.
+	Moves(directions []string) (mazelib.Reply, error)
+	// Done tells Daedalus that Icarus won't solve any more mazes
+	Done() error
+}
+
+// newTransport picks the transport given by --transport
+func newTransport() (transport, error) {
+	switch viper.GetString("transport") {
+	case "http":
+		base, err := daedalusAddress()
+		if err != nil {
+			return nil, err
+		}
+		return &httpTransport{base: base, retries: viper.GetInt("retries")}, nil
+	case "websocket":
+		base, err := daedalusAddress()
+		if err != nil {
+			return nil, err
+		}
+		retries := viper.GetInt("retries")
+		return &socketTransport{http: &httpTransport{base: base, retries: retries}, retries: retries}, nil
+	case "direct":
+		return newDirectTransport()
+	}
+	return nil, fmt.Errorf("unknown transport %q (available: http, websocket, direct)", viper.GetString("transport"))
+}
+
+// httpTransport talks to a Daedalus server
+type httpTransport struct {
+	// address of daedalus, including the base path
+	base *url.URL
+	// session given by daedalus on the first awake
+	session string
+	// times to retry a call which couldn't connect
+	retries int
+}
+
+// daedalusAddress returns the address of the server given by --server,
+// or the local one listening on --port if there isn't any
+func daedalusAddress() (*url.URL, error) {
+	s := viper.GetString("server")
+	if s == "" {
+		s = "http://127.0.0.1:" + viper.GetString("port")
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("server %q must be an http or https URL", s)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("server %q has no host", s)
+	}
+	return u, nil
+}
+
+// url builds the address of an endpoint under the base path of the server,
+// carrying the session if there is one
+func (t *httpTransport) url(path string) string {
+	u := *t.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = ""
+	if t.session != "" {
+		u.RawQuery = url.Values{"session": {t.session}}.Encode()
+	}
+	return u.String()
+}
+
+// Bounds of the delay between two polls of /healthz
+const (
+	minPollDelay = 10 * time.Millisecond
+	maxPollDelay = 500 * time.Millisecond
+)
+
+// Wait polls /healthz until it answers, backing off exponentially.
+// It's the only call safe to repeat when daedalus can't be reached.
+func (t *httpTransport) Wait(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for delay := minPollDelay; ; {
+		var err error
+		response, getErr := http.Get(t.url("/healthz"))
+		if getErr != nil {
+			err = &TransportError{Op: "wait", Err: getErr}
+		} else {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = &ProtocolError{Op: "wait", Status: response.StatusCode, Message: "daedalus isn't ready"}
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		time.Sleep(delay)
+		if delay *= 2; delay > maxPollDelay {
+			delay = maxPollDelay
+		}
+	}
+}
+
+func (t *httpTransport) Awake() (mazelib.Reply, error) {
+	r, err := t.call("awake", "/awake")
+	if err != nil {
+		return r, err
+	}
+	t.session = r.Session
+	return r, nil
+}
+
+func (t *httpTransport) Move(direction string) (mazelib.Reply, error) {
+	return t.call("move", "/move/"+direction)
+}
+
+func (t *httpTransport) Moves(directions []string) (mazelib.Reply, error) {
+	body, err := json.Marshal(movesRequest{Directions: directions})
+	if err != nil {
+		return mazelib.Reply{}, err
+	}
+	return t.send("moves", func() ([]byte, int, error) {
+		return postRequest(t.url("/moves"), body)
+	})
+}
+
+func (t *httpTransport) Done() error {
+	_, err := t.call("done", "/done")
+	return err
+}
+
+// call makes a request to daedalus and decodes the reply
+func (t *httpTransport) call(op, path string) (mazelib.Reply, error) {
+	return t.send(op, func() ([]byte, int, error) {
+		return makeRequest(t.url(path))
+	})
+}
+
+// send makes the request, again if it couldn't connect, and decodes the reply
+func (t *httpTransport) send(op string, request func() ([]byte, int, error)) (mazelib.Reply, error) {
+	var contents []byte
+	var status int
+	err := retry(t.retries, func() error {
+		var err error
+		if contents, status, err = request(); err != nil {
+			return &TransportError{Op: op, Err: err, Unsent: dialFailed(err)}
+		}
+		return nil
+	})
+	if err != nil {
+		return mazelib.Reply{}, err
+	}
+	return t.reply(op, contents, status)
+}
+
+// reply decodes the reply of daedalus
+func (t *httpTransport) reply(op string, contents []byte, status int) (mazelib.Reply, error) {
+	r, err := ToReply(contents)
+	if err != nil {
+		return mazelib.Reply{}, &ProtocolError{Op: op, Status: status, Message: err.Error()}
+	}
+	return checkReply(op, r, status)
+}
+
+// utility function to wrap making requests to the daedalus server
+func makeRequest(url string) ([]byte, int, error) {
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return contents, response.StatusCode, nil
+}
+
+// postRequest sends JSON to the daedalus server
+func postRequest(url string, body []byte) ([]byte, int, error) {
+	response, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return contents, response.StatusCode, nil
+}
+
+// Handling a JSON response and unmarshalling it into a reply struct
+func ToReply(in []byte) (mazelib.Reply, error) {
+	res := mazelib.Reply{}
+	err := json.Unmarshal(in, &res)
+	return res, err
+}
+
+// retryDelay is the delay before the first retry, it doubles after each one
+const retryDelay = 100 * time.Millisecond
+
+// retry calls f again as long as it couldn't connect to daedalus, at most n more times
+func retry(n int, f func() error) error {
+	err := f()
+	for i, delay := 0, retryDelay; i < n; i, delay = i+1, delay*2 {
+		if e, ok := err.(*TransportError); !ok || !e.Unsent {
+			break
+		}
+		time.Sleep(delay)
+		err = f()
+	}
+	return err
+}
+
+// dialFailed tells whether the error is a failure to connect, before anything was sent
+func dialFailed(err error) bool {
+	var op *net.OpError
+	return errors.As(err, &op) && op.Op == "dial"
+}
+
+// directTransport calls the mazes in memory, without any server.
+// It keeps its own sessions, and prints the results when done.
+type directTransport struct {
+	sessions *sessionStore
+	session  string
+}
+
+func newDirectTransport() (*directTransport, error) {
+	if err := checkMazeSource(); err != nil {
+		return nil, err
+	}
+	return &directTransport{sessions: newSessionStore(0, true)}, nil
+}
+
+// Wait returns at once, the mazes are always there
+func (t *directTransport) Wait(timeout time.Duration) error {
+	return nil
+}
+
+func (t *directTransport) Awake() (mazelib.Reply, error) {
+	r, status := t.sessions.awake(t.session, "")
+	r, err := checkReply("awake", r, status)
+	if err != nil {
+		return r, err
+	}
+	t.session = r.Session
+	return r, nil
+}
+
+func (t *directTransport) Move(direction string) (mazelib.Reply, error) {
+	r, status := t.sessions.move(t.session, direction)
+	return checkReply("move", r, status)
+}
+
+func (t *directTransport) Moves(directions []string) (mazelib.Reply, error) {
+	r, status := t.sessions.moves(t.session, directions)
+	return checkReply("moves", r, status)
+}
+
+func (t *directTransport) Done() error {
+	r, status, last := t.sessions.end(t.session)
+	if last {
+		writeResults(t.sessions.results())
+	}
+	t.sessions.finish()
+	_, err := checkReply("done", r, status)
+	return err
+}
@@ -0,0 +1,63 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{"success", nil, 1},
+		{"unsent", &TransportError{Op: "move", Err: errors.New("refused"), Unsent: true}, 3},
+		{"sent", &TransportError{Op: "move", Err: errors.New("reset")}, 1},
+		{"refused move", &IllegalMoveError{Direction: "up"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retry(2, func() error {
+				calls++
+				return tt.err
+			})
+			if err != tt.err || calls != tt.calls {
+				t.Errorf("retry returned %v after %d calls, want %v after %d", err, calls, tt.err, tt.calls)
+			}
+		})
+	}
+}
+
+// A call to a port nobody listens on never reaches daedalus
+func TestUnsentCall(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("http://" + ln.Addr().String())
+	ln.Close()
+
+	_, err = (&httpTransport{base: base}).Awake()
+	if e, ok := err.(*TransportError); !ok || !e.Unsent {
+		t.Errorf("awake failed with %#v, want an unsent TransportError", err)
+	}
+}
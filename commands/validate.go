@@ -0,0 +1,102 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/showbufire/gc6/mazelib"
+	"github.com/spf13/cobra"
+)
+
+// Defining the validate command.
+// This will be called as 'laybrinth validate <file>'
+var validateCmd = &cobra.Command{
+	Use:   "validate <file>",
+	Short: "Check that a saved laybrinth is well-formed and solvable",
+	Long: `Validate reads a laybrinth saved as JSON, or drawn in a .txt file the way
+  daedalus prints it, and checks that the walls agree between neighboring
+  rooms, the outer boundary is closed, there is exactly one start and one
+  treasure, and the treasure can be reached from the start.
+  It also tells whether the laybrinth is perfect, that is without loops,
+  and how difficult it is to solve.
+
+  It exits with a non-zero status if any problem is found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println("validate takes exactly one file")
+			os.Exit(-1)
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+		ok, err := validateMaze(args[0], asJSON)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	validateCmd.Flags().Bool("json", false, "print the report as JSON")
+	RootCmd.AddCommand(validateCmd)
+}
+
+// validateMaze prints the report about a saved maze, and tells whether it's fine
+func validateMaze(path string, asJSON bool) (bool, error) {
+	m, err := loadMaze(path)
+	if err != nil {
+		return false, err
+	}
+	r, err := mazelib.Validate(m)
+	if err != nil {
+		return false, err
+	}
+	// there's no difficulty to measure if the maze can't be solved
+	var d *mazelib.Difficulty
+	if r.OK() {
+		if d, err = mazelib.Measure(m); err != nil {
+			return false, err
+		}
+	}
+
+	if asJSON {
+		b, err := json.MarshalIndent(struct {
+			*mazelib.Report
+			Difficulty *mazelib.Difficulty `json:"difficulty,omitempty"`
+		}{r, d}, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		fmt.Println(string(b))
+		return r.OK(), nil
+	}
+
+	for _, f := range r.Findings {
+		fmt.Println(f)
+	}
+	fmt.Printf("%s: %d problems, %d of %d rooms reachable, solvable: %v, loops: %d, perfect: %v\n",
+		path, len(r.Findings), r.Reachable, m.Width()*m.Height(), r.Solvable, r.Loops, r.Perfect)
+	if d != nil {
+		fmt.Printf("difficulty: score %.2f, solution %d steps, expected %.1f steps, %d dead ends %.1f rooms deep, branching %.2f, river %.2f\n",
+			d.Score, d.SolutionLength, d.ExpectedSteps, d.DeadEnds, d.DeadEndDepth, d.BranchingFactor, d.RiverFactor)
+	}
+	return r.OK(), nil
+}
@@ -0,0 +1,249 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/showbufire/gc6/mazelib"
+)
+
+// heartbeat keeps the event streams of idle viewers open through proxies
+const heartbeat = 15 * time.Second
+
+// Streams the events of a session, or of all the sessions, as Server-Sent Events.
+// A viewer first gets the maze of every session, then what happens in them.
+func Events(c *gin.Context) {
+	id := c.Query("session")
+	ch, ok := sessions.events.subscribe(id)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, mazelib.Reply{Error: true, Message: "daedalus is stopping"})
+		return
+	}
+	defer sessions.events.unsubscribe(ch)
+
+	c.Header("Cache-Control", "no-cache")
+	last := map[string]event{}
+	for _, e := range sessions.snapshot(id) {
+		c.SSEvent(e.Type, e)
+		last[e.Session] = e
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-ch:
+			// dropped for being too slow, the browser reconnects and gets a new snapshot
+			if !ok {
+				return
+			}
+			if prev, seen := last[e.Session]; seen && e.stale(prev) {
+				continue
+			}
+			c.SSEvent(e.Type, e)
+			last[e.Session] = e
+		case <-ticker.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// Serves the page to watch the sessions in a browser
+func Viewer(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(viewerPage))
+}
+
+// viewerPage draws the maze of a session from the events, with the colors of the SVG images.
+// It follows the latest session to awake, unless one is picked.
+const viewerPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Labyrinth</title>
+<style>
+  body { font-family: sans-serif; margin: 1em; }
+  #status { margin: 0.5em 0; }
+</style>
+</head>
+<body>
+<label>Session <select id="session"><option value="">latest</option></select></label>
+<div id="status">Waiting for Icarus to awake...</div>
+<canvas id="maze"></canvas>
+<script>
+var cell = 20;
+var sessions = {}, latest = "", follow = "", pending = false;
+var select = document.getElementById("session");
+var statusLine = document.getElementById("status");
+var canvas = document.getElementById("maze");
+var ctx = canvas.getContext("2d");
+
+select.onchange = function() { follow = select.value; schedule(); };
+
+function schedule() {
+  if (!pending) {
+    pending = true;
+    window.requestAnimationFrame(function() { pending = false; draw(); });
+  }
+}
+
+function onMaze(e) {
+  if (!sessions[e.session]) {
+    var option = document.createElement("option");
+    option.value = e.session;
+    option.text = e.session.substring(0, 8);
+    select.appendChild(option);
+  }
+  sessions[e.session] = {
+    id: e.session, maze: e.maze, route: e.route || [e.icarus], icarus: e.icarus,
+    steps: e.steps, optimal: e.optimal, state: "solving"
+  };
+  latest = e.session;
+  schedule();
+}
+
+function onMove(e) {
+  var s = sessions[e.session];
+  if (!s) {
+    return;
+  }
+  if (e.exhausted) {
+    s.state = "out of steps";
+  } else {
+    s.route.push(e.icarus);
+    s.icarus = e.icarus;
+    s.steps = e.steps;
+    if (e.victory) {
+      s.state = "victory";
+    }
+  }
+  schedule();
+}
+
+function onEnd(e) {
+  var s = sessions[e.session];
+  if (s) {
+    s.state = "done";
+  }
+  schedule();
+}
+
+function center(c) {
+  return [c.x * cell + cell / 2, c.y * cell + cell / 2];
+}
+
+function draw() {
+  var s = sessions[follow || latest];
+  if (!s) {
+    return;
+  }
+  var m = s.maze;
+  statusLine.textContent = "Session " + s.id.substring(0, 8) + ", seed " + (m.seed || 0) + ": " +
+    s.steps + " steps, the shortest path has " + s.optimal + ", " + s.state;
+
+  canvas.width = (m.width + 1) * cell;
+  canvas.height = (m.height + 1) * cell;
+  ctx.setTransform(1, 0, 0, 1, cell / 2, cell / 2);
+  ctx.fillStyle = "white";
+  ctx.fillRect(0, 0, m.width * cell, m.height * cell);
+
+  var visits = {}, most = 0;
+  s.route.forEach(function(c) {
+    var k = c.x + "," + c.y;
+    visits[k] = (visits[k] || 0) + 1;
+    most = Math.max(most, visits[k]);
+  });
+  for (var k in visits) {
+    var xy = k.split(",");
+    ctx.fillStyle = "rgba(74, 144, 217, " + (0.15 + 0.45 * visits[k] / most) + ")";
+    ctx.fillRect(xy[0] * cell, xy[1] * cell, cell, cell);
+  }
+
+  // a wall is drawn when either of the rooms it separates has it
+  ctx.strokeStyle = "black";
+  ctx.lineWidth = 2;
+  ctx.lineCap = "square";
+  ctx.beginPath();
+  for (var y = 0; y < m.height; y++) {
+    for (var x = 0; x < m.width; x++) {
+      var w = m.rooms[y][x];
+      var x0 = x * cell, y0 = y * cell, x1 = x0 + cell, y1 = y0 + cell;
+      if (w.top || (y > 0 && m.rooms[y - 1][x].bottom)) {
+        ctx.moveTo(x0, y0); ctx.lineTo(x1, y0);
+      }
+      if (w.left || (x > 0 && m.rooms[y][x - 1].right)) {
+        ctx.moveTo(x0, y0); ctx.lineTo(x0, y1);
+      }
+      if (w.right && x == m.width - 1) {
+        ctx.moveTo(x1, y0); ctx.lineTo(x1, y1);
+      }
+      if (w.bottom && y == m.height - 1) {
+        ctx.moveTo(x0, y1); ctx.lineTo(x1, y1);
+      }
+    }
+  }
+  ctx.stroke();
+
+  ctx.strokeStyle = "rgba(208, 2, 27, 0.7)";
+  ctx.lineJoin = "round";
+  ctx.beginPath();
+  s.route.forEach(function(c, i) {
+    var p = center(c);
+    if (i == 0) {
+      ctx.moveTo(p[0], p[1]);
+    } else {
+      ctx.lineTo(p[0], p[1]);
+    }
+  });
+  ctx.stroke();
+
+  var p = center(m.start);
+  ctx.fillStyle = "#2e9e44";
+  ctx.beginPath();
+  ctx.arc(p[0], p[1], cell / 3, 0, 2 * Math.PI);
+  ctx.fill();
+
+  p = center(m.treasure);
+  var r = cell / 3;
+  ctx.fillStyle = "#f5a623";
+  ctx.beginPath();
+  ctx.moveTo(p[0], p[1] - r); ctx.lineTo(p[0] + r, p[1]); ctx.lineTo(p[0], p[1] + r); ctx.lineTo(p[0] - r, p[1]);
+  ctx.closePath();
+  ctx.fill();
+
+  p = center(s.icarus);
+  ctx.fillStyle = "#7b2cbf";
+  ctx.beginPath();
+  ctx.arc(p[0], p[1], cell / 4, 0, 2 * Math.PI);
+  ctx.fill();
+}
+
+// the page passes its query on, so that /viewer?session=<id> follows a single session
+var source = new EventSource("events" + window.location.search);
+source.addEventListener("maze", function(m) { onMaze(JSON.parse(m.data)); });
+source.addEventListener("move", function(m) { onMove(JSON.parse(m.data)); });
+source.addEventListener("end", function(m) { onEnd(JSON.parse(m.data)); });
+source.onerror = function() { statusLine.textContent = "Lost daedalus, reconnecting..."; };
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,300 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/showbufire/gc6/common"
+	"github.com/showbufire/gc6/mazelib"
+	"github.com/spf13/cobra"
+)
+
+// Defining the watch command.
+// This will be called as 'laybrinth watch'
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch Icarus solve a laybrinth in the terminal",
+	Long: `Watch runs Icarus against daedalus, through the --transport like the
+  icarus command, and draws what he knows of the laybrinth in the terminal
+  after every move: where he is, the rooms he has explored, the rooms he
+  may backtrack to and the steps he took.
+
+  Use --transport direct to watch him without a server, in laybrinths
+  generated in memory or read from the --maze-dir.
+
+  Press space to pause or resume, n to take a single step while paused,
+  + and - to go faster or slower, and q to quit. Where the terminal can't
+  be set to read single keys, press enter after them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 {
+			fmt.Println("watch takes no argument")
+			os.Exit(-1)
+		}
+		delay, _ := cmd.Flags().GetDuration("delay")
+		if err := watch(interruptContext(), delay); err != nil {
+			fmt.Println(err)
+			os.Exit(exitCode(err))
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().Duration("delay", 100*time.Millisecond, "time between moves")
+	RootCmd.AddCommand(watchCmd)
+}
+
+// Bounds of the time between moves
+const (
+	minWatchDelay = time.Millisecond
+	maxWatchDelay = 5 * time.Second
+)
+
+// errQuit stops a watch before Icarus is done
+var errQuit = errors.New("quit")
+
+// stacker is a solver that keeps the rooms it may backtrack to
+type stacker interface {
+	// Stack returns the rooms from where Icarus awoke, the latest last
+	Stack() []mazelib.Coordinate
+}
+
+// watcher draws what Icarus learns of a maze, and handles the keys pressed meanwhile.
+// The rooms are counted from where he awoke, he doesn't know the rest of the maze.
+type watcher struct {
+	ctx    context.Context
+	solver mazelib.Solver
+	// keys pressed, nil if the input isn't a terminal.
+	// In line mode, they are only read once enter is pressed.
+	keys     <-chan byte
+	lineMode bool
+	delay    time.Duration
+	paused   bool
+
+	// seen are the walls of the rooms Icarus entered, route the rooms in order
+	seen     map[mazelib.Coordinate]mazelib.Survey
+	route    []mazelib.Coordinate
+	treasure bool
+	// clear tells to clear the screen, for a new maze
+	clear bool
+}
+
+func watch(ctx context.Context, delay time.Duration) error {
+	w := &watcher{ctx: ctx, delay: delay}
+	if isTerminal(os.Stdin) {
+		restore, err := cbreak()
+		if err == nil {
+			defer restore()
+		} else {
+			w.lineMode = true
+		}
+		w.keys = readKeys()
+	}
+	fmt.Print("\x1b[?25l")
+	defer fmt.Print("\x1b[?25h")
+
+	err := runIcarus(w.entered)
+	if err == errQuit {
+		return nil
+	}
+	return err
+}
+
+// entered records the room Icarus entered, draws it, then waits for the next move
+func (w *watcher) entered(solver mazelib.Solver, dir int, survey mazelib.Survey, victory bool) error {
+	if dir == 0 {
+		// a new maze, with a new solver
+		w.solver = solver
+		w.seen = map[mazelib.Coordinate]mazelib.Survey{}
+		w.route = nil
+		w.treasure = false
+		w.clear = true
+	}
+	c := common.Coordinate{}
+	if len(w.route) > 0 {
+		c = common.Coordinate{w.route[len(w.route)-1]}.Neighbor(dir)
+	}
+	w.route = append(w.route, c.Coordinate)
+	w.seen[c.Coordinate] = survey
+	w.treasure = victory
+	w.draw()
+
+	var tick <-chan time.Time
+	if !w.paused {
+		tick = time.After(w.delay)
+	}
+	for {
+		select {
+		case <-tick:
+			return nil
+		case <-w.ctx.Done():
+			return errQuit
+		case k := <-w.keys:
+			switch k {
+			case ' ':
+				w.paused = !w.paused
+				tick = nil
+				if !w.paused {
+					tick = time.After(w.delay)
+				}
+			case 'n':
+				if w.paused {
+					return nil
+				}
+			case '+':
+				if w.delay /= 2; w.delay < minWatchDelay {
+					w.delay = minWatchDelay
+				}
+			case '-':
+				if w.delay *= 2; w.delay > maxWatchDelay {
+					w.delay = maxWatchDelay
+				}
+			case 'q':
+				return errQuit
+			}
+			w.draw()
+		}
+	}
+}
+
+// known builds the part of the maze Icarus has seen, and where its first room is from where he awoke.
+// The rooms he hasn't entered have no walls.
+func (w *watcher) known() (*Maze, mazelib.Coordinate) {
+	min, max := w.route[0], w.route[0]
+	for c := range w.seen {
+		if c.X < min.X {
+			min.X = c.X
+		}
+		if c.Y < min.Y {
+			min.Y = c.Y
+		}
+		if c.X > max.X {
+			max.X = c.X
+		}
+		if c.Y > max.Y {
+			max.Y = c.Y
+		}
+	}
+	m := &Maze{rooms: make([][]mazelib.Room, max.Y-min.Y+1)}
+	for y := range m.rooms {
+		m.rooms[y] = make([]mazelib.Room, max.X-min.X+1)
+	}
+	for c, survey := range w.seen {
+		m.rooms[c.Y-min.Y][c.X-min.X].Walls = survey
+	}
+	m.rooms[-min.Y][-min.X].Start = true
+	if w.treasure {
+		last := w.route[len(w.route)-1]
+		m.rooms[last.Y-min.Y][last.X-min.X].Treasure = true
+	}
+	return m, min
+}
+
+// draw redraws the screen, from its top left corner
+func (w *watcher) draw() {
+	m, min := w.known()
+	shift := func(c mazelib.Coordinate) mazelib.Coordinate {
+		return mazelib.Coordinate{c.X - min.X, c.Y - min.Y}
+	}
+	o := &mazelib.Overlay{Start: true, Treasure: w.treasure, Revealed: map[mazelib.Coordinate]bool{}}
+	for c := range w.seen {
+		o.Revealed[shift(c)] = true
+	}
+	icarus := shift(w.route[len(w.route)-1])
+	o.Icarus = &icarus
+	stack := 0
+	if s, ok := w.solver.(stacker); ok {
+		for _, c := range s.Stack() {
+			o.Trajectory = append(o.Trajectory, shift(c))
+		}
+		stack = len(o.Trajectory)
+	}
+
+	var b bytes.Buffer
+	if w.clear {
+		b.WriteString("\x1b[2J")
+		w.clear = false
+	}
+	b.WriteString("\x1b[H")
+	if err := mazelib.WriteText(&b, m, o, true); err != nil {
+		fmt.Println(err)
+		return
+	}
+	state := "running"
+	if w.paused {
+		state = "paused"
+	}
+	if w.treasure {
+		state = "found the treasure"
+	}
+	fmt.Fprintf(&b, "step %d  explored %d  stack %d  delay %v  %s\x1b[K\n",
+		len(w.route)-1, len(w.seen), stack, w.delay, state)
+	if w.keys != nil {
+		b.WriteString("space pause/resume  n step  + faster  - slower  q quit")
+		if w.lineMode {
+			b.WriteString(", then enter")
+		}
+		b.WriteString("\x1b[K\n")
+	}
+	b.WriteString("\x1b[J")
+	os.Stdout.Write(b.Bytes())
+}
+
+// cbreak makes the keys pressed readable one at a time, without echoing them.
+// It returns how to restore the terminal.
+func cbreak() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+// stty sets the terminal of the standard input
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %v", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// readKeys sends the keys pressed, until the input is closed
+func readKeys() <-chan byte {
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil {
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+	return keys
+}
@@ -0,0 +1,164 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The glyphs PrintMaze writes for the rooms, the second rune is the bottom wall
+var asciiRooms = map[string]struct {
+	start, treasure, bottom bool
+}{
+	"  ": {},
+	"__": {bottom: true},
+	"⏀ ": {start: true},
+	"⏂_": {start: true, bottom: true},
+	"⏃ ": {treasure: true},
+	"⏅_": {treasure: true, bottom: true},
+}
+
+// ParseASCII reads a maze in the text PrintMaze writes, for instance
+//
+//	_______
+//	|⏂__  |
+//	|__|⏅_|
+//
+// is a 2x2 maze with the start at the top left, the treasure at the bottom right,
+// and no way from one to the other but through the top right room.
+// PrintMaze only writes the bottom and right walls of the rooms, the top and left ones
+// are taken from the neighbors. Trailing blank lines are ignored.
+func ParseASCII(r io.Reader) (*Document, error) {
+	var lines [][]rune
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines = append(lines, []rune(strings.TrimRight(sc.Text(), "\r")))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for len(lines) > 0 && strings.TrimSpace(string(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) < 2 {
+		return nil, errors.New("a maze has a top line and at least a row of rooms")
+	}
+
+	top := string(lines[0])
+	width := (len(lines[0]) - 1) / 3
+	if width == 0 || top != "_"+strings.Repeat("___", width) {
+		return nil, fmt.Errorf("line 1: expecting the top of the maze, like _%s", strings.Repeat("___", 3))
+	}
+
+	d := &Document{Version: DocumentVersion, Width: width, Height: len(lines) - 1}
+	starts, treasures := 0, 0
+	for y, line := range lines[1:] {
+		n := y + 2
+		if len(line) != 1+3*width {
+			return nil, fmt.Errorf("line %d: %d characters, expecting %d", n, len(line), 1+3*width)
+		}
+		if line[0] != '|' {
+			return nil, fmt.Errorf("line %d: expecting the left wall of the maze, found %q", n, line[0])
+		}
+		row := make([]Survey, width)
+		for x := 0; x < width; x++ {
+			cell := line[1+3*x : 4+3*x]
+			room, ok := asciiRooms[string(cell[:2])]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown room %q", n, string(cell[:2]))
+			}
+			switch cell[2] {
+			case '|':
+				row[x].Right = true
+			case '_':
+			default:
+				return nil, fmt.Errorf("line %d: expecting a wall | or _ after room %d, found %q", n, x, cell[2])
+			}
+			row[x].Bottom = room.bottom
+			row[x].Left = x == 0 || row[x-1].Right
+			row[x].Top = y == 0 || d.Rooms[y-1][x].Bottom
+			if room.start {
+				d.Start = Coordinate{x, y}
+				starts++
+			}
+			if room.treasure {
+				d.Treasure = Coordinate{x, y}
+				treasures++
+			}
+		}
+		d.Rooms = append(d.Rooms, row)
+	}
+
+	if starts != 1 || treasures != 1 {
+		return nil, fmt.Errorf("found %d starts and %d treasures, expecting one of each", starts, treasures)
+	}
+	return d, d.Check()
+}
+
+// WriteASCII writes the maze as text, the way PrintMaze does. ParseASCII reads it back.
+func WriteASCII(w io.Writer, m MazeI) error {
+	if _, err := fmt.Fprintln(w, "_"+strings.Repeat("___", m.Width())); err != nil {
+		return err
+	}
+	for y := 0; y < m.Height(); y++ {
+		str := ""
+		for x := 0; x < m.Width(); x++ {
+			if x == 0 {
+				str += "|"
+			}
+			r, err := m.GetRoom(x, y)
+			if err != nil {
+				return err
+			}
+			s, err := m.Discover(x, y)
+			if err != nil {
+				return err
+			}
+			if s.Bottom {
+				if r.Treasure {
+					str += "⏅_"
+				} else if r.Start {
+					str += "⏂_"
+				} else {
+					str += "__"
+				}
+			} else {
+				if r.Treasure {
+					str += "⏃ "
+				} else if r.Start {
+					str += "⏀ "
+				} else {
+					str += "  "
+				}
+			}
+
+			if s.Right {
+				str += "|"
+			} else {
+				str += "_"
+			}
+
+		}
+		if _, err := fmt.Fprintln(w, str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
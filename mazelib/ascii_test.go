@@ -0,0 +1,88 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// The fixture is a maze printed by daedalus, made by rectcut with the seed 7
+func TestParseASCIIRoundTrip(t *testing.T) {
+	want, err := ioutil.ReadFile("testdata/rectcut-6x4.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ParseASCII(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("ParseASCII: %v", err)
+	}
+	if d.Width != 6 || d.Height != 4 {
+		t.Errorf("size %dx%d, want 6x4", d.Width, d.Height)
+	}
+	if d.Start != (Coordinate{2, 2}) {
+		t.Errorf("start %v, want (2, 2)", d.Start)
+	}
+	if d.Treasure != (Coordinate{3, 3}) {
+		t.Errorf("treasure %v, want (3, 3)", d.Treasure)
+	}
+
+	m := newTestMaze(d)
+	r, err := Validate(m)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if !r.OK() || !r.Perfect {
+		t.Errorf("the maze should be valid and perfect, got %+v", r)
+	}
+
+	var got bytes.Buffer
+	if err := WriteASCII(&got, m); err != nil {
+		t.Fatalf("WriteASCII: %v", err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("printed\n%s\nwant\n%s", got.String(), want)
+	}
+}
+
+func TestParseASCIIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		// part of the message expected
+		err string
+	}{
+		{"empty", "", "top line"},
+		{"no rooms", "_______\n", "top line"},
+		{"bad top", "___\n|⏂__  |\n", "line 1"},
+		{"short row", "_______\n|⏂__  |\n|__|\n", "line 3"},
+		{"no left wall", "_______\n ⏂__  |\n|__|⏅_|\n", "left wall"},
+		{"unknown room", "_______\n|⏂__xx|\n|__|⏅_|\n", "unknown room"},
+		{"bad right wall", "_______\n|⏂_x  |\n|__|⏅_|\n", "expecting a wall"},
+		{"no treasure", "_______\n|⏂__  |\n|__|__|\n", "0 treasures"},
+		{"two starts", "_______\n|⏂__⏀ |\n|__|⏅_|\n", "2 starts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseASCII(strings.NewReader(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseASCII(%q) error %v, want one with %q", tt.in, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// How many random walks estimate the expected steps of a random DFS
+const dfsTrials = 32
+
+// Difficulty describes how hard a maze is to solve for Icarus
+type Difficulty struct {
+	// SolutionLength is the number of steps of the shortest path to the treasure
+	SolutionLength int `json:"solution_length"`
+	// DeadEnds are the rooms with a single way out, other than the start and the treasure
+	DeadEnds int `json:"dead_ends"`
+	// BranchingFactor is the average number of ways forward from the rooms offering a choice
+	BranchingFactor float64 `json:"branching_factor"`
+	// RiverFactor is the share of the rooms that are corridors, with exactly two ways out.
+	// Mazes with a high river factor have long passages and few decisions.
+	RiverFactor float64 `json:"river_factor"`
+	// DeadEndDepth is the average number of rooms from a dead end back to a choice
+	DeadEndDepth float64 `json:"dead_end_depth"`
+	// ExpectedSteps is the average number of steps of a random depth-first search
+	ExpectedSteps float64 `json:"expected_steps"`
+	// Score is the expected steps over the number of reachable rooms, the higher the harder
+	Score float64 `json:"score"`
+}
+
+// Measure computes the difficulty of a maze, moving the way Icarus does.
+// The same maze always gets the same measures.
+func Measure(m MazeI) (*Difficulty, error) {
+	start, treasure, err := landmarks(m)
+	if err != nil {
+		return nil, err
+	}
+	dist, err := Distances(m, start)
+	if err != nil {
+		return nil, err
+	}
+	length, ok := dist[treasure]
+	if !ok {
+		return nil, errors.New("the treasure can't be reached from the start")
+	}
+
+	d := &Difficulty{SolutionLength: length}
+	graph := map[Coordinate][]Coordinate{}
+	for c := range dist {
+		if graph[c], err = exits(m, c); err != nil {
+			return nil, err
+		}
+	}
+
+	corridors, junctions, forward, depths := 0, 0, 0, 0
+	for c, nbs := range graph {
+		switch n := len(nbs); {
+		case n == 1 && c != start && c != treasure:
+			d.DeadEnds++
+			depths += deadEndDepth(graph, c, start, treasure)
+		case n == 2:
+			corridors++
+		case n > 2:
+			junctions++
+			forward += n - 1
+		}
+	}
+	if junctions > 0 {
+		d.BranchingFactor = float64(forward) / float64(junctions)
+	}
+	if d.DeadEnds > 0 {
+		d.DeadEndDepth = float64(depths) / float64(d.DeadEnds)
+	}
+	d.RiverFactor = float64(corridors) / float64(len(graph))
+
+	rnd := rand.New(rand.NewSource(1))
+	total := 0
+	for i := 0; i < dfsTrials; i++ {
+		total += randomDFS(graph, start, treasure, rnd)
+	}
+	d.ExpectedSteps = float64(total) / dfsTrials
+	d.Score = d.ExpectedSteps / float64(len(graph))
+	return d, nil
+}
+
+// deadEndDepth follows the corridor from a dead end, until a room offering a choice
+func deadEndDepth(graph map[Coordinate][]Coordinate, c, start, treasure Coordinate) int {
+	depth, prev := 1, c
+	c = graph[c][0]
+	for len(graph[c]) == 2 && c != start && c != treasure {
+		next := graph[c][0]
+		if next == prev {
+			next = graph[c][1]
+		}
+		prev, c = c, next
+		depth++
+	}
+	return depth
+}
+
+// randomDFS counts the steps to the treasure of a walk that takes a random unexplored way,
+// and goes back when there's none
+func randomDFS(graph map[Coordinate][]Coordinate, start, treasure Coordinate, rnd *rand.Rand) int {
+	explored := map[Coordinate]bool{start: true}
+	path := []Coordinate{start}
+	steps := 0
+	for len(path) > 0 {
+		c := path[len(path)-1]
+		if c == treasure {
+			return steps
+		}
+		var ways []Coordinate
+		for _, nb := range graph[c] {
+			if !explored[nb] {
+				ways = append(ways, nb)
+			}
+		}
+		if len(ways) == 0 {
+			path = path[:len(path)-1]
+		} else {
+			nb := ways[rnd.Intn(len(ways))]
+			explored[nb] = true
+			path = append(path, nb)
+		}
+		steps++
+	}
+	return steps
+}
+
+// landmarks finds the start and the treasure of a maze
+func landmarks(m MazeI) (start, treasure Coordinate, err error) {
+	starts, treasures := 0, 0
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			room, err := m.GetRoom(x, y)
+			if err != nil {
+				return start, treasure, err
+			}
+			if room.Start {
+				start = Coordinate{x, y}
+				starts++
+			}
+			if room.Treasure {
+				treasure = Coordinate{x, y}
+				treasures++
+			}
+		}
+	}
+	if starts != 1 || treasures != 1 {
+		err = fmt.Errorf("found %d starts and %d treasures, expecting one of each", starts, treasures)
+	}
+	return start, treasure, err
+}
+
+// Band is a range of difficulty scores
+type Band struct {
+	Name string
+	Min  float64
+	Max  float64
+}
+
+// Bands are the named ranges of difficulty scores.
+// They split the mazes of the default generator, rectcut, in about thirds:
+// its scores are mostly between 0.8 and 1.2, whatever the size of the maze.
+var Bands = []Band{
+	{"easy", 0, 0.95},
+	{"medium", 0.95, 1.02},
+	{"hard", 1.02, 0},
+}
+
+// ParseBand reads a named band, or a range of scores such as 0.9-1.1.
+// A missing bound is open.
+func ParseBand(s string) (Band, error) {
+	for _, b := range Bands {
+		if b.Name == s {
+			return b, nil
+		}
+	}
+
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return Band{}, fmt.Errorf("unknown difficulty %q, expecting %s, or a range of scores like 0.9-1.1", s, bandNames())
+	}
+	b := Band{Name: s}
+	var err error
+	if parts[0] != "" {
+		if b.Min, err = strconv.ParseFloat(parts[0], 64); err != nil {
+			return Band{}, fmt.Errorf("bad difficulty %q: %v", s, err)
+		}
+	}
+	if parts[1] != "" {
+		if b.Max, err = strconv.ParseFloat(parts[1], 64); err != nil {
+			return Band{}, fmt.Errorf("bad difficulty %q: %v", s, err)
+		}
+		if b.Max <= b.Min {
+			return Band{}, fmt.Errorf("bad difficulty %q: the range is empty", s)
+		}
+	}
+	return b, nil
+}
+
+// Contains tells whether the score is in the band. A zero Max has no upper bound.
+func (b Band) Contains(score float64) bool {
+	return score >= b.Min && (b.Max == 0 || score < b.Max)
+}
+
+func bandNames() string {
+	names := []string{}
+	for _, b := range Bands {
+		names = append(names, b.Name)
+	}
+	return strings.Join(names, ", ")
+}
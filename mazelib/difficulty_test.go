@@ -0,0 +1,70 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import "testing"
+
+func TestParseBand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Band
+		err  bool
+	}{
+		{in: "easy", want: Bands[0]},
+		{in: "medium", want: Bands[1]},
+		{in: "hard", want: Bands[2]},
+		{in: "0.9-1.1", want: Band{"0.9-1.1", 0.9, 1.1}},
+		{in: "1-", want: Band{"1-", 1, 0}},
+		{in: "-0.8", want: Band{"-0.8", 0, 0.8}},
+		{in: "impossible", err: true},
+		{in: "0.5", err: true},
+		{in: "0.5-0.7-0.9", err: true},
+		{in: "a-1", err: true},
+		{in: "0-b", err: true},
+		{in: "0.7-0.5", err: true},
+		{in: "0.7-0.7", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseBand(tt.in)
+			if tt.err {
+				if err == nil {
+					t.Errorf("ParseBand(%q) = %+v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBand(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBand(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBandContains(t *testing.T) {
+	b := Band{Min: 0.9, Max: 1.1}
+	for score, want := range map[float64]bool{0.89: false, 0.9: true, 1: true, 1.1: false} {
+		if got := b.Contains(score); got != want {
+			t.Errorf("%+v.Contains(%v) = %v, want %v", b, score, got, want)
+		}
+	}
+	open := Band{Min: 1}
+	if !open.Contains(100) {
+		t.Errorf("%+v has no upper bound, it should contain 100", open)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DocumentVersion is the version of the maze schema written by this package.
+// Readers reject documents of any other version.
+const DocumentVersion = 1
+
+// Document is the JSON form of a maze.
+// Rooms are listed row by row, so a room is found at Rooms[y][x].
+type Document struct {
+	Version  int        `json:"version"`
+	Width    int        `json:"width"`
+	Height   int        `json:"height"`
+	Start    Coordinate `json:"start"`
+	Treasure Coordinate `json:"treasure"`
+	Seed     int64      `json:"seed,omitempty"`
+	Rooms    [][]Survey `json:"rooms"`
+}
+
+// NewDocument describes any maze as a document
+func NewDocument(m MazeI) (*Document, error) {
+	d := &Document{
+		Version: DocumentVersion,
+		Width:   m.Width(),
+		Height:  m.Height(),
+		Rooms:   make([][]Survey, m.Height()),
+	}
+	start, treasure := false, false
+	for y := 0; y < m.Height(); y++ {
+		d.Rooms[y] = make([]Survey, m.Width())
+		for x := 0; x < m.Width(); x++ {
+			r, err := m.GetRoom(x, y)
+			if err != nil {
+				return nil, err
+			}
+			d.Rooms[y][x] = r.Walls
+			if r.Start {
+				d.Start = Coordinate{x, y}
+				start = true
+			}
+			if r.Treasure {
+				d.Treasure = Coordinate{x, y}
+				treasure = true
+			}
+		}
+	}
+	if !start {
+		return nil, errors.New("the maze has no start")
+	}
+	if !treasure {
+		return nil, errors.New("the maze has no treasure")
+	}
+	return d, nil
+}
+
+// Check tells whether the document is consistent with itself
+func (d *Document) Check() error {
+	if d.Version != DocumentVersion {
+		return fmt.Errorf("unsupported maze version %d, expecting %d", d.Version, DocumentVersion)
+	}
+	if d.Width <= 0 || d.Height <= 0 {
+		return fmt.Errorf("bad maze size %dx%d", d.Width, d.Height)
+	}
+	if len(d.Rooms) != d.Height {
+		return fmt.Errorf("the maze has %d rows of rooms, expecting %d", len(d.Rooms), d.Height)
+	}
+	for y, row := range d.Rooms {
+		if len(row) != d.Width {
+			return fmt.Errorf("row %d has %d rooms, expecting %d", y, len(row), d.Width)
+		}
+	}
+	inside := func(c Coordinate) bool {
+		return 0 <= c.X && c.X < d.Width && 0 <= c.Y && c.Y < d.Height
+	}
+	if !inside(d.Start) {
+		return fmt.Errorf("start %v is outside of the maze", d.Start)
+	}
+	if !inside(d.Treasure) {
+		return fmt.Errorf("treasure %v is outside of the maze", d.Treasure)
+	}
+	if d.Start == d.Treasure {
+		return errors.New("can't have the treasure at the start")
+	}
+	return nil
+}
+
+// WriteDocument writes the document as indented JSON
+func WriteDocument(w io.Writer, d *Document) error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadDocument reads a document, and checks it
+func ReadDocument(r io.Reader) (*Document, error) {
+	d := &Document{}
+	if err := json.NewDecoder(r).Decode(d); err != nil {
+		return nil, err
+	}
+	if err := d.Check(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,77 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Generator carves the walls of a maze.
+// The start and the treasure are already set when Generate is called.
+// All the randomness must come from rnd, so that a maze can be rebuilt from its seed.
+type Generator interface {
+	Generate(m MazeI, start, treasure Coordinate, rnd *rand.Rand) error
+}
+
+var (
+	generatorsMu sync.RWMutex
+	generators   = make(map[string]Generator)
+)
+
+// RegisterGenerator makes a generator available by name.
+// It panics if the name is registered twice, or the generator is nil.
+func RegisterGenerator(name string, g Generator) {
+	generatorsMu.Lock()
+	defer generatorsMu.Unlock()
+	if g == nil {
+		panic("mazelib: RegisterGenerator generator is nil")
+	}
+	if _, dup := generators[name]; dup {
+		panic("mazelib: RegisterGenerator called twice for generator " + name)
+	}
+	generators[name] = g
+}
+
+// GetGenerator returns the generator registered with the name
+func GetGenerator(name string) (Generator, error) {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+	g, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q (available: %s)", name, strings.Join(generatorNames(), ", "))
+	}
+	return g, nil
+}
+
+// Generators returns the sorted names of the registered generators
+func Generators() []string {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+	return generatorNames()
+}
+
+func generatorNames() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
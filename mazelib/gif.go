@@ -0,0 +1,102 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// Shades of the heat map in the palette of the replays
+const heatShades = 16
+
+// WriteGIF replays the trajectory of the overlay as an animation, a frame per move.
+// The rooms are revealed as Icarus enters them, and the visits are counted as he goes
+// if the overlay has any. The delay between frames is in 100ths of a second,
+// the last frame lasts longer.
+func WriteGIF(w io.Writer, m MazeI, o *Overlay, s Style, delay int) error {
+	if o == nil || len(o.Trajectory) == 0 {
+		return errors.New("a replay needs the trajectory of Icarus")
+	}
+
+	p := palette(s)
+	anim := &gif.GIF{}
+	var prev *image.Paletted
+	frame := &Overlay{Start: o.Start, Treasure: o.Treasure, Revealed: map[Coordinate]bool{}}
+	if o.Visits != nil {
+		frame.Visits = map[Coordinate]int{}
+	}
+	for i, c := range o.Trajectory {
+		frame.Trajectory = o.Trajectory[:i+1]
+		frame.Revealed[c] = true
+		if frame.Visits != nil {
+			frame.Visits[c]++
+		}
+		icarus := c
+		frame.Icarus = &icarus
+
+		img, err := Draw(m, frame, s)
+		if err != nil {
+			return err
+		}
+		pal := image.NewPaletted(img.Bounds(), p)
+		draw.Draw(pal, pal.Bounds(), img, image.Point{}, draw.Src)
+		// every frame is drawn over the previous one, only what changed is kept
+		if prev != nil {
+			changed := changes(prev, pal)
+			prev = pal
+			pal = pal.SubImage(changed).(*image.Paletted)
+		} else {
+			prev = pal
+		}
+		anim.Image = append(anim.Image, pal)
+		anim.Delay = append(anim.Delay, delay)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	anim.Delay[len(anim.Delay)-1] = 10 * delay
+	return gif.EncodeAll(w, anim)
+}
+
+// changes returns the smallest rectangle holding the pixels that differ between the frames.
+// It isn't empty even when nothing changed, GIF frames can't be empty.
+func changes(a, b *image.Paletted) image.Rectangle {
+	r := image.Rectangle{}
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if a.ColorIndexAt(x, y) != b.ColorIndexAt(x, y) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if r.Empty() {
+		r = image.Rect(0, 0, 1, 1)
+	}
+	return r
+}
+
+// palette has the colors of the style, and the shades of the heat map
+func palette(s Style) color.Palette {
+	p := color.Palette{s.Background, s.Walls, s.Start, s.Treasure, s.Trajectory, s.Fog, s.Icarus}
+	for i := 0; i < heatShades; i++ {
+		p = append(p, blend(s.Background, s.Heat, 0.25+0.75*float64(i+1)/heatShades))
+	}
+	return p
+}
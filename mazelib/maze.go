@@ -20,9 +20,7 @@ package mazelib
 
 import (
 	"errors"
-	"fmt"
 	"os"
-	"strings"
 )
 
 // Coordinate describes a location in the maze
@@ -37,6 +35,16 @@ type Reply struct {
 	Victory bool   `json:"victory"`
 	Message string `json:"message"`
 	Error   bool   `json:"error"`
+	// Exhausted is set when Icarus has run out of steps
+	Exhausted bool   `json:"exhausted"`
+	Session   string `json:"session,omitempty"`
+	// Seed of the maze, given on awake
+	Seed int64 `json:"seed,omitempty"`
+	// Steps taken, and the fewest possible, given on victory
+	Steps   int `json:"steps,omitempty"`
+	Optimal int `json:"optimal,omitempty"`
+	// Surveys of the rooms entered, one per step, when many moves are made at once
+	Surveys []Survey `json:"surveys,omitempty"`
 }
 
 // Survey Given a location, survey surrounding locations
@@ -57,6 +65,10 @@ const (
 
 var ErrVictory error = errors.New("Victory")
 
+var ErrMaxSteps error = errors.New("Out of steps")
+
+var ErrUnknownDirection error = errors.New("Unknown direction")
+
 // Room contains the minimum informaion about a room in the maze.
 type Room struct {
 	Treasure bool
@@ -121,49 +133,6 @@ func AvgScores(in []int) int {
 }
 
 // PrintMaze : Function to Print Maze to Console
-func PrintMaze(m MazeI) {
-	fmt.Println("_" + strings.Repeat("___", m.Width()))
-	for y := 0; y < m.Height(); y++ {
-		str := ""
-		for x := 0; x < m.Width(); x++ {
-			if x == 0 {
-				str += "|"
-			}
-			r, err := m.GetRoom(x, y)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(-1)
-			}
-			s, err := m.Discover(x, y)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(-1)
-			}
-			if s.Bottom {
-				if r.Treasure {
-					str += "⏅_"
-				} else if r.Start {
-					str += "⏂_"
-				} else {
-					str += "__"
-				}
-			} else {
-				if r.Treasure {
-					str += "⏃ "
-				} else if r.Start {
-					str += "⏀ "
-				} else {
-					str += "  "
-				}
-			}
-
-			if s.Right {
-				str += "|"
-			} else {
-				str += "_"
-			}
-
-		}
-		fmt.Println(str)
-	}
+func PrintMaze(m MazeI) error {
+	return WriteASCII(os.Stdout, m)
 }
@@ -0,0 +1,202 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Style is how a maze is drawn as a raster image
+type Style struct {
+	// Cell is the size of a room, and Wall the thickness of the walls, in pixels
+	Cell int
+	Wall int
+
+	Background color.RGBA
+	Walls      color.RGBA
+	Start      color.RGBA
+	Treasure   color.RGBA
+	Trajectory color.RGBA
+	// Heat is the color of the most visited rooms, the others fade to the background
+	Heat color.RGBA
+	// Fog hides the rooms that aren't revealed
+	Fog    color.RGBA
+	Icarus color.RGBA
+}
+
+// check tells whether the rooms and the walls can be drawn at their sizes
+func (s Style) check() error {
+	if s.Cell < 3 || s.Wall < 1 || s.Wall >= s.Cell {
+		return errors.New("the rooms must be at least 3 pixels, and the walls thinner than the rooms")
+	}
+	return nil
+}
+
+// DefaultStyle is the style of the images unless told otherwise
+var DefaultStyle = Style{
+	Cell:       20,
+	Wall:       2,
+	Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+	Walls:      color.RGBA{0x00, 0x00, 0x00, 0xff},
+	Start:      color.RGBA{0x2e, 0x9e, 0x44, 0xff},
+	Treasure:   color.RGBA{0xf5, 0xa6, 0x23, 0xff},
+	Trajectory: color.RGBA{0xd0, 0x02, 0x1b, 0xff},
+	Heat:       color.RGBA{0x4a, 0x90, 0xd9, 0xff},
+	Fog:        color.RGBA{0x55, 0x55, 0x55, 0xff},
+	Icarus:     color.RGBA{0x7b, 0x2c, 0xbf, 0xff},
+}
+
+// Draw draws the maze as an image in the style, with the overlay if it isn't nil.
+// The rooms are drawn under the walls, and the trajectory and the landmarks over them.
+func Draw(m MazeI, o *Overlay, s Style) (*image.RGBA, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	if o == nil {
+		o = &Overlay{}
+	}
+	start, treasure, err := o.landmarks(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// the walls are centered on the edges of the rooms, the margin makes room for the outer ones
+	off := s.Wall / 2
+	img := image.NewRGBA(image.Rect(0, 0, m.Width()*s.Cell+s.Wall, m.Height()*s.Cell+s.Wall))
+	fill(img, img.Bounds(), s.Background)
+	room := func(c Coordinate) image.Rectangle {
+		return image.Rect(off+c.X*s.Cell, off+c.Y*s.Cell, off+(c.X+1)*s.Cell, off+(c.Y+1)*s.Cell)
+	}
+	center := func(c Coordinate) image.Point {
+		return image.Pt(off+c.X*s.Cell+s.Cell/2, off+c.Y*s.Cell+s.Cell/2)
+	}
+
+	most := 0
+	for _, n := range o.Visits {
+		if n > most {
+			most = n
+		}
+	}
+	for c, n := range o.Visits {
+		if n > 0 {
+			fill(img, room(c), blend(s.Background, s.Heat, 0.25+0.75*float64(n)/float64(most)))
+		}
+	}
+	if o.Revealed != nil {
+		for y := 0; y < m.Height(); y++ {
+			for x := 0; x < m.Width(); x++ {
+				if c := (Coordinate{x, y}); !o.Revealed[c] {
+					fill(img, room(c), s.Fog)
+				}
+			}
+		}
+	}
+
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			walls, err := sharedWalls(m, x, y)
+			if err != nil {
+				return nil, err
+			}
+			c := Coordinate{x, y}
+			r := room(c)
+			// every wall spans the corners, so that they join
+			if walls.Top && (o.shown(c) || o.shown(Coordinate{x, y - 1})) {
+				fill(img, image.Rect(r.Min.X-off, r.Min.Y-off, r.Max.X-off+s.Wall, r.Min.Y-off+s.Wall), s.Walls)
+			}
+			if walls.Left && (o.shown(c) || o.shown(Coordinate{x - 1, y})) {
+				fill(img, image.Rect(r.Min.X-off, r.Min.Y-off, r.Min.X-off+s.Wall, r.Max.Y-off+s.Wall), s.Walls)
+			}
+			if walls.Right && x == m.Width()-1 && o.shown(c) {
+				fill(img, image.Rect(r.Max.X-off, r.Min.Y-off, r.Max.X-off+s.Wall, r.Max.Y-off+s.Wall), s.Walls)
+			}
+			if walls.Bottom && y == m.Height()-1 && o.shown(c) {
+				fill(img, image.Rect(r.Min.X-off, r.Max.Y-off, r.Max.X-off+s.Wall, r.Max.Y-off+s.Wall), s.Walls)
+			}
+		}
+	}
+
+	// Icarus moves a room at a time, so the trajectory is made of straight segments
+	thick := s.Wall
+	for i := 1; i < len(o.Trajectory); i++ {
+		a, b := center(o.Trajectory[i-1]), center(o.Trajectory[i])
+		seg := image.Rectangle{a, b}.Canon()
+		fill(img, image.Rect(seg.Min.X-thick/2, seg.Min.Y-thick/2, seg.Max.X-thick/2+thick, seg.Max.Y-thick/2+thick), s.Trajectory)
+	}
+
+	radius := s.Cell / 3
+	if o.Start {
+		c := center(start)
+		mark(img, c, radius, s.Start, func(dx, dy int) bool { return dx*dx+dy*dy <= radius*radius })
+	}
+	if o.Treasure {
+		c := center(treasure)
+		mark(img, c, radius, s.Treasure, func(dx, dy int) bool { return abs(dx)+abs(dy) <= radius })
+	}
+	if o.Icarus != nil {
+		r := s.Cell / 4
+		mark(img, center(*o.Icarus), r, s.Icarus, func(dx, dy int) bool { return dx*dx+dy*dy <= r*r })
+	}
+	return img, nil
+}
+
+// WritePNG draws the maze as a PNG image, in the style and with the overlay if it isn't nil
+func WritePNG(w io.Writer, m MazeI, o *Overlay, s Style) error {
+	img, err := Draw(m, o, s)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// mark fills the pixels around the center for which the shape is true
+func mark(img *image.RGBA, center image.Point, radius int, c color.RGBA, shape func(dx, dy int) bool) {
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if shape(dx, dy) {
+				img.SetRGBA(center.X+dx, center.Y+dy, c)
+			}
+		}
+	}
+}
+
+// blend mixes the colors, t is the share of the second one
+func blend(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x)*(1-t) + float64(y)*t + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
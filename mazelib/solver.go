@@ -0,0 +1,81 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Solver decides where Icarus goes.
+// It only knows what Icarus has seen so far: a survey of every room he entered.
+type Solver interface {
+	// Start is called when Icarus awakes, with the survey of his room
+	Start(s Survey)
+	// Next returns the moves to make, in order. Each one is N, S, E or W.
+	Next() ([]int, error)
+	// Moved is called after every move, with the survey of the room Icarus entered
+	Moved(s Survey)
+}
+
+var (
+	solversMu sync.RWMutex
+	solvers   = make(map[string]func() Solver)
+)
+
+// RegisterSolver makes a solver available by name.
+// A solver keeps track of a single maze, so a new one is made for every maze.
+// It panics if the name is registered twice, or the constructor is nil.
+func RegisterSolver(name string, newSolver func() Solver) {
+	solversMu.Lock()
+	defer solversMu.Unlock()
+	if newSolver == nil {
+		panic("mazelib: RegisterSolver constructor is nil")
+	}
+	if _, dup := solvers[name]; dup {
+		panic("mazelib: RegisterSolver called twice for solver " + name)
+	}
+	solvers[name] = newSolver
+}
+
+// NewSolver makes a solver of the name
+func NewSolver(name string) (Solver, error) {
+	solversMu.RLock()
+	defer solversMu.RUnlock()
+	newSolver, ok := solvers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown solver %q (available: %s)", name, strings.Join(solverNames(), ", "))
+	}
+	return newSolver(), nil
+}
+
+// Solvers returns the sorted names of the registered solvers
+func Solvers() []string {
+	solversMu.RLock()
+	defer solversMu.RUnlock()
+	return solverNames()
+}
+
+func solverNames() []string {
+	names := make([]string, 0, len(solvers))
+	for name := range solvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
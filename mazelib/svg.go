@@ -0,0 +1,218 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// Overlay is what is drawn over the walls of a rendered maze
+type Overlay struct {
+	// Start and Treasure mark the rooms of the start and the treasure
+	Start    bool
+	Treasure bool
+	// Trajectory is the rooms a solver went through, in order
+	Trajectory []Coordinate
+	// Visits counts how many times a solver entered each room
+	Visits map[Coordinate]int
+	// Revealed, when not nil, are the only rooms shown, the others are in the fog
+	Revealed map[Coordinate]bool
+	// Icarus, when not nil, marks where Icarus is
+	Icarus *Coordinate
+}
+
+// shown tells whether the room is out of the fog
+func (o *Overlay) shown(c Coordinate) bool {
+	return o.Revealed == nil || o.Revealed[c]
+}
+
+// landmarks finds the start and the treasure, there must be one of those the overlay marks
+func (o *Overlay) landmarks(m MazeI) (start, treasure Coordinate, err error) {
+	starts, treasures := 0, 0
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			room, err := m.GetRoom(x, y)
+			if err != nil {
+				return start, treasure, err
+			}
+			if room.Start {
+				start = Coordinate{x, y}
+				starts++
+			}
+			if room.Treasure {
+				treasure = Coordinate{x, y}
+				treasures++
+			}
+		}
+	}
+	if o.Start && starts != 1 {
+		return start, treasure, fmt.Errorf("found %d starts, expecting one", starts)
+	}
+	if o.Treasure && treasures != 1 {
+		return start, treasure, fmt.Errorf("found %d treasures, expecting one", treasures)
+	}
+	return start, treasure, nil
+}
+
+// CountVisits counts how many times each room appears in the trajectory
+func CountVisits(trajectory []Coordinate) map[Coordinate]int {
+	visits := map[Coordinate]int{}
+	for _, c := range trajectory {
+		visits[c]++
+	}
+	return visits
+}
+
+// WriteSVG draws the maze as an SVG image in the style, with the overlay if it isn't nil.
+// A wall is drawn when either of the rooms it separates has it.
+func WriteSVG(w io.Writer, m MazeI, o *Overlay, s Style) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if o == nil {
+		o = &Overlay{}
+	}
+	start, treasure, err := o.landmarks(m)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	cell := s.Cell
+	width, height := m.Width()*cell, m.Height()*cell
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="%d %d %d %d">`+"\n",
+		width+cell, height+cell, -cell/2, -cell/2, width+cell, height+cell)
+	fmt.Fprintf(&b, `<rect x="0" y="0" width="%d" height="%d" fill="%s"/>`+"\n", width, height, svgColor(s.Background))
+
+	most := 0
+	for _, n := range o.Visits {
+		if n > most {
+			most = n
+		}
+	}
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			if !o.shown(Coordinate{x, y}) {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+					x*cell, y*cell, cell, cell, svgColor(s.Fog))
+			} else if n := o.Visits[Coordinate{x, y}]; n > 0 {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" fill-opacity="%.2f"/>`+"\n",
+					x*cell, y*cell, cell, cell, svgColor(s.Heat), 0.15+0.45*float64(n)/float64(most))
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, `<g stroke="%s" stroke-width="%d" stroke-linecap="square">`+"\n", svgColor(s.Walls), s.Wall)
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			walls, err := sharedWalls(m, x, y)
+			if err != nil {
+				return err
+			}
+			c := Coordinate{x, y}
+			x0, y0, x1, y1 := x*cell, y*cell, (x+1)*cell, (y+1)*cell
+			if walls.Top && (o.shown(c) || o.shown(Coordinate{x, y - 1})) {
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n", x0, y0, x1, y0)
+			}
+			if walls.Left && (o.shown(c) || o.shown(Coordinate{x - 1, y})) {
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n", x0, y0, x0, y1)
+			}
+			if walls.Right && x == m.Width()-1 && o.shown(c) {
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n", x1, y0, x1, y1)
+			}
+			if walls.Bottom && y == m.Height()-1 && o.shown(c) {
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n", x0, y1, x1, y1)
+			}
+		}
+	}
+	fmt.Fprintln(&b, `</g>`)
+
+	if len(o.Trajectory) > 0 {
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="%d" stroke-opacity="0.7" stroke-linejoin="round" points="`,
+			svgColor(s.Trajectory), s.Wall)
+		for i, c := range o.Trajectory {
+			if i > 0 {
+				fmt.Fprint(&b, " ")
+			}
+			fmt.Fprintf(&b, "%d,%d", c.X*cell+cell/2, c.Y*cell+cell/2)
+		}
+		fmt.Fprintln(&b, `"/>`)
+	}
+
+	if o.Start {
+		fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="%d" fill="%s"/>`+"\n",
+			start.X*cell+cell/2, start.Y*cell+cell/2, cell/3, svgColor(s.Start))
+	}
+	if o.Treasure {
+		cx, cy, r := treasure.X*cell+cell/2, treasure.Y*cell+cell/2, cell/3
+		fmt.Fprintf(&b, `<polygon points="%d,%d %d,%d %d,%d %d,%d" fill="%s" stroke="%s"/>`+"\n",
+			cx, cy-r, cx+r, cy, cx, cy+r, cx-r, cy, svgColor(s.Treasure), svgColor(blend(s.Treasure, s.Walls, 0.45)))
+	}
+	if o.Icarus != nil {
+		fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="%d" fill="%s"/>`+"\n",
+			o.Icarus.X*cell+cell/2, o.Icarus.Y*cell+cell/2, cell/4, svgColor(s.Icarus))
+	}
+	fmt.Fprintln(&b, `</svg>`)
+
+	_, err = b.WriteTo(w)
+	return err
+}
+
+// svgColor writes a color the way SVG attributes take it
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// sharedWalls returns the walls of a room, adding those its neighbors have on their side
+func sharedWalls(m MazeI, x, y int) (Survey, error) {
+	room, err := m.GetRoom(x, y)
+	if err != nil {
+		return Survey{}, err
+	}
+	walls := room.Walls
+	if y > 0 {
+		above, err := m.GetRoom(x, y-1)
+		if err != nil {
+			return Survey{}, err
+		}
+		walls.Top = walls.Top || above.Walls.Bottom
+	}
+	if x > 0 {
+		left, err := m.GetRoom(x-1, y)
+		if err != nil {
+			return Survey{}, err
+		}
+		walls.Left = walls.Left || left.Walls.Right
+	}
+	if y < m.Height()-1 {
+		below, err := m.GetRoom(x, y+1)
+		if err != nil {
+			return Survey{}, err
+		}
+		walls.Bottom = walls.Bottom || below.Walls.Top
+	}
+	if x < m.Width()-1 {
+		right, err := m.GetRoom(x+1, y)
+		if err != nil {
+			return Survey{}, err
+		}
+		walls.Right = walls.Right || right.Walls.Left
+	}
+	return walls, nil
+}
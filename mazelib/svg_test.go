@@ -0,0 +1,51 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestWriteSVGStyle(t *testing.T) {
+	m := parseTestMaze(t, "_______\n|⏀ _  |\n|__|⏅_|\n")
+	s := DefaultStyle
+	s.Cell = 30
+	s.Wall = 4
+	s.Walls = color.RGBA{0xff, 0, 0, 0xff}
+	s.Heat = color.RGBA{0, 0xff, 0, 0xff}
+
+	var b bytes.Buffer
+	if err := WriteSVG(&b, m, &Overlay{Visits: map[Coordinate]int{{0, 0}: 1}}, s); err != nil {
+		t.Fatalf("WriteSVG: %v", err)
+	}
+	for _, want := range []string{
+		`width="90" height="90"`,
+		`<g stroke="#ff0000" stroke-width="4"`,
+		`width="30" height="30" fill="#00ff00"`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("the SVG has no %s:\n%s", want, b.String())
+		}
+	}
+
+	s.Wall = s.Cell
+	if err := WriteSVG(&b, m, nil, s); err == nil {
+		t.Error("walls as thick as the rooms should be refused")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"bytes"
+	"io"
+)
+
+// ANSI escape codes of the colors of the text
+const (
+	ansiReset    = "\x1b[0m"
+	ansiStart    = "\x1b[1;32m"
+	ansiTreasure = "\x1b[1;33m"
+	ansiIcarus   = "\x1b[1;35m"
+	ansiPath     = "\x1b[36m"
+	ansiFog      = "\x1b[90m"
+)
+
+// junctions are the box-drawing characters joining walls, indexed by the walls
+// going up, down, left and right, from the lowest bit
+var junctions = []rune(" ╵╷│╴┘┐┤╶└┌├─┴┬┼")
+
+// WriteText draws the maze with box-drawing characters, marking the landmarks,
+// Icarus and the trajectory of the overlay if it isn't nil, and shading the rooms
+// in the fog. Colors are ANSI escape codes.
+// A wall is drawn when either of the rooms it separates has it.
+func WriteText(w io.Writer, m MazeI, o *Overlay, color bool) error {
+	if o == nil {
+		o = &Overlay{}
+	}
+	start, treasure, err := o.landmarks(m)
+	if err != nil {
+		return err
+	}
+	width, height := m.Width(), m.Height()
+
+	// hwalls[y][x] is the wall above the room (x, y), vwalls[y][x] the wall at its left.
+	// The walls below the last row, and right of the last column, come last.
+	hwalls := make([][]bool, height+1)
+	vwalls := make([][]bool, height)
+	for y := 0; y <= height; y++ {
+		hwalls[y] = make([]bool, width)
+		if y < height {
+			vwalls[y] = make([]bool, width+1)
+		}
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			walls, err := sharedWalls(m, x, y)
+			if err != nil {
+				return err
+			}
+			hwalls[y][x] = walls.Top
+			hwalls[y+1][x] = hwalls[y+1][x] || walls.Bottom
+			vwalls[y][x] = walls.Left
+			vwalls[y][x+1] = vwalls[y][x+1] || walls.Right
+		}
+	}
+
+	// the walls between rooms in the fog are hidden, and the fog fills the space between them
+	fog := func(x, y int) bool {
+		return 0 <= x && x < width && 0 <= y && y < height && !o.shown(Coordinate{x, y})
+	}
+	for y := 0; y <= height; y++ {
+		for x := 0; x <= width; x++ {
+			if x < width && !o.shown(Coordinate{x, y - 1}) && !o.shown(Coordinate{x, y}) {
+				hwalls[y][x] = false
+			}
+			if y < height && !o.shown(Coordinate{x - 1, y}) && !o.shown(Coordinate{x, y}) {
+				vwalls[y][x] = false
+			}
+		}
+	}
+	paint := func(s, code string) string {
+		if color && code != "" {
+			return code + s + ansiReset
+		}
+		return s
+	}
+
+	path := map[Coordinate]bool{}
+	for _, c := range o.Trajectory {
+		path[c] = true
+	}
+
+	var b bytes.Buffer
+	for y := 0; y <= height; y++ {
+		for x := 0; x <= width; x++ {
+			j := 0
+			if y > 0 && vwalls[y-1][x] {
+				j |= 1
+			}
+			if y < height && vwalls[y][x] {
+				j |= 2
+			}
+			if x > 0 && hwalls[y][x-1] {
+				j |= 4
+			}
+			if x < width && hwalls[y][x] {
+				j |= 8
+			}
+			if j == 0 && fog(x-1, y-1) && fog(x, y-1) && fog(x-1, y) && fog(x, y) {
+				b.WriteString(paint("░", ansiFog))
+			} else {
+				b.WriteRune(junctions[j])
+			}
+			if x < width {
+				switch {
+				case hwalls[y][x]:
+					b.WriteString("───")
+				case fog(x, y-1) && fog(x, y):
+					b.WriteString(paint("░░░", ansiFog))
+				default:
+					b.WriteString("   ")
+				}
+			}
+		}
+		b.WriteByte('\n')
+		if y == height {
+			break
+		}
+
+		for x := 0; x <= width; x++ {
+			switch {
+			case vwalls[y][x]:
+				b.WriteString("│")
+			case fog(x-1, y) && fog(x, y):
+				b.WriteString(paint("░", ansiFog))
+			default:
+				b.WriteString(" ")
+			}
+			if x == width {
+				break
+			}
+			c := Coordinate{x, y}
+			glyph, code := " ", ""
+			switch {
+			case o.Icarus != nil && *o.Icarus == c:
+				glyph, code = "@", ansiIcarus
+			case o.Treasure && c == treasure:
+				glyph, code = "T", ansiTreasure
+			case o.Start && c == start:
+				glyph, code = "S", ansiStart
+			case path[c]:
+				glyph, code = "·", ansiPath
+			case fog(x, y):
+				b.WriteString(paint("░░░", ansiFog))
+				continue
+			}
+			b.WriteString(" " + paint(glyph, code) + " ")
+		}
+		b.WriteByte('\n')
+	}
+
+	_, err = b.WriteTo(w)
+	return err
+}
@@ -0,0 +1,305 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import "fmt"
+
+// Names of the checks done by Validate
+const (
+	CheckSymmetry  = "symmetry"
+	CheckBoundary  = "boundary"
+	CheckStart     = "start"
+	CheckTreasure  = "treasure"
+	CheckReachable = "reachable"
+)
+
+// Finding is a problem found in a maze
+type Finding struct {
+	Check   string     `json:"check"`
+	Room    Coordinate `json:"room"`
+	Message string     `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: room (%d, %d): %s", f.Check, f.Room.X, f.Room.Y, f.Message)
+}
+
+// Report is the result of validating a maze
+type Report struct {
+	Findings []Finding `json:"findings"`
+	// Rooms reachable from the start, moving the way Icarus does
+	Reachable int  `json:"reachable"`
+	Solvable  bool `json:"solvable"`
+	// Loops is the number of independent loops through open passages
+	Loops int `json:"loops"`
+	// Perfect mazes connect every room, with no loops
+	Perfect bool `json:"perfect"`
+}
+
+// OK tells whether the maze is well-formed and solvable
+func (r *Report) OK() bool {
+	return len(r.Findings) == 0
+}
+
+func (r *Report) add(check string, c Coordinate, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{Check: check, Room: c, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks that the walls of the maze agree between neighbors, the outer boundary is closed,
+// there's exactly one start and one treasure, and the treasure can be reached from the start.
+// It also tells whether the maze is perfect.
+func Validate(m MazeI) (*Report, error) {
+	r := &Report{Findings: []Finding{}}
+	w, h := m.Width(), m.Height()
+
+	var starts, treasures []Coordinate
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := Coordinate{x, y}
+			room, err := m.GetRoom(x, y)
+			if err != nil {
+				return nil, err
+			}
+			if room.Start {
+				starts = append(starts, c)
+			}
+			if room.Treasure {
+				treasures = append(treasures, c)
+			}
+
+			if y == 0 && !room.Walls.Top {
+				r.add(CheckBoundary, c, "no wall at the top of the maze")
+			}
+			if y == h-1 && !room.Walls.Bottom {
+				r.add(CheckBoundary, c, "no wall at the bottom of the maze")
+			}
+			if x == 0 && !room.Walls.Left {
+				r.add(CheckBoundary, c, "no wall at the left of the maze")
+			}
+			if x == w-1 && !room.Walls.Right {
+				r.add(CheckBoundary, c, "no wall at the right of the maze")
+			}
+
+			if x+1 < w {
+				right, err := m.GetRoom(x+1, y)
+				if err != nil {
+					return nil, err
+				}
+				if room.Walls.Right != right.Walls.Left {
+					r.add(CheckSymmetry, c, "right wall is %v, but left wall of (%d, %d) is %v", room.Walls.Right, x+1, y, right.Walls.Left)
+				}
+			}
+			if y+1 < h {
+				below, err := m.GetRoom(x, y+1)
+				if err != nil {
+					return nil, err
+				}
+				if room.Walls.Bottom != below.Walls.Top {
+					r.add(CheckSymmetry, c, "bottom wall is %v, but top wall of (%d, %d) is %v", room.Walls.Bottom, x, y+1, below.Walls.Top)
+				}
+			}
+		}
+	}
+
+	if len(starts) != 1 {
+		r.add(CheckStart, Coordinate{}, "found %d starts, expecting 1", len(starts))
+	}
+	if len(treasures) != 1 {
+		r.add(CheckTreasure, Coordinate{}, "found %d treasures, expecting 1", len(treasures))
+	}
+	for _, s := range starts {
+		for _, t := range treasures {
+			if s == t {
+				r.add(CheckTreasure, t, "the treasure is at the start")
+			}
+		}
+	}
+
+	if len(starts) > 0 {
+		dist, err := Distances(m, starts[0])
+		if err != nil {
+			return nil, err
+		}
+		r.Reachable = len(dist)
+		if len(treasures) > 0 {
+			_, r.Solvable = dist[treasures[0]]
+			if !r.Solvable {
+				r.add(CheckReachable, treasures[0], "the treasure can't be reached from the start")
+			}
+		}
+	}
+
+	loops, components, err := countLoops(m)
+	if err != nil {
+		return nil, err
+	}
+	r.Loops = loops
+	r.Perfect = loops == 0 && components == 1
+	return r, nil
+}
+
+// step returns the coordinate next to c in the direction
+func step(c Coordinate, dir int) Coordinate {
+	switch dir {
+	case N:
+		c.Y--
+	case S:
+		c.Y++
+	case E:
+		c.X++
+	case W:
+		c.X--
+	}
+	return c
+}
+
+// hasWall tells whether there is a wall in the direction
+func (s Survey) hasWall(dir int) bool {
+	switch dir {
+	case N:
+		return s.Top
+	case S:
+		return s.Bottom
+	case E:
+		return s.Right
+	case W:
+		return s.Left
+	}
+	return true
+}
+
+// exits returns the rooms Icarus can move to from c.
+// Like the server, only the walls of the room he is in stop him.
+func exits(m MazeI, c Coordinate) ([]Coordinate, error) {
+	room, err := m.GetRoom(c.X, c.Y)
+	if err != nil {
+		return nil, err
+	}
+	ret := []Coordinate{}
+	for _, dir := range []int{N, S, E, W} {
+		nb := step(c, dir)
+		if room.Walls.hasWall(dir) || nb.X < 0 || nb.Y < 0 || nb.X >= m.Width() || nb.Y >= m.Height() {
+			continue
+		}
+		ret = append(ret, nb)
+	}
+	return ret, nil
+}
+
+// Distances returns the number of steps from the room to every room reachable from it
+func Distances(m MazeI, from Coordinate) (map[Coordinate]int, error) {
+	dist := map[Coordinate]int{from: 0}
+	queue := []Coordinate{from}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		nbs, err := exits(m, c)
+		if err != nil {
+			return nil, err
+		}
+		for _, nb := range nbs {
+			if _, seen := dist[nb]; !seen {
+				dist[nb] = dist[c] + 1
+				queue = append(queue, nb)
+			}
+		}
+	}
+	return dist, nil
+}
+
+// ShortestPath returns the rooms of a shortest way between the rooms, both included.
+// It returns nil if there is no way.
+func ShortestPath(m MazeI, from, to Coordinate) ([]Coordinate, error) {
+	parent := map[Coordinate]Coordinate{from: from}
+	queue := []Coordinate{from}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if c == to {
+			path := []Coordinate{c}
+			for c != from {
+				c = parent[c]
+				path = append([]Coordinate{c}, path...)
+			}
+			return path, nil
+		}
+		nbs, err := exits(m, c)
+		if err != nil {
+			return nil, err
+		}
+		for _, nb := range nbs {
+			if _, seen := parent[nb]; !seen {
+				parent[nb] = c
+				queue = append(queue, nb)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// countLoops counts the independent loops, and the connected components, of the graph of the rooms
+// linked by passages open on both sides
+func countLoops(m MazeI) (int, int, error) {
+	w, h := m.Width(), m.Height()
+	parent := make([]int, w*h)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	passages, components := 0, w*h
+	link := func(i, j int) {
+		passages++
+		if ri, rj := find(i), find(j); ri != rj {
+			parent[ri] = rj
+			components--
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			room, err := m.GetRoom(x, y)
+			if err != nil {
+				return 0, 0, err
+			}
+			if x+1 < w {
+				right, err := m.GetRoom(x+1, y)
+				if err != nil {
+					return 0, 0, err
+				}
+				if !room.Walls.Right && !right.Walls.Left {
+					link(y*w+x, y*w+x+1)
+				}
+			}
+			if y+1 < h {
+				below, err := m.GetRoom(x, y+1)
+				if err != nil {
+					return 0, 0, err
+				}
+				if !room.Walls.Bottom && !below.Walls.Top {
+					link(y*w+x, (y+1)*w+x)
+				}
+			}
+		}
+	}
+	// a forest with n rooms and c trees has n-c passages, every extra one closes a loop
+	return passages - (w*h - components), components, nil
+}
@@ -0,0 +1,215 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package mazelib
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testMaze is a MazeI holding the rooms of a document, Icarus moves freely through them
+type testMaze struct {
+	rooms  [][]Room
+	icarus Coordinate
+}
+
+func newTestMaze(d *Document) *testMaze {
+	m := &testMaze{rooms: make([][]Room, d.Height), icarus: d.Start}
+	for y, row := range d.Rooms {
+		m.rooms[y] = make([]Room, d.Width)
+		for x, walls := range row {
+			m.rooms[y][x].Walls = walls
+		}
+	}
+	m.rooms[d.Start.Y][d.Start.X].Start = true
+	m.rooms[d.Treasure.Y][d.Treasure.X].Treasure = true
+	return m
+}
+
+// parseTestMaze reads a maze drawn the way PrintMaze does
+func parseTestMaze(t *testing.T, s string) *testMaze {
+	d, err := ParseASCII(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("ParseASCII: %v", err)
+	}
+	return newTestMaze(d)
+}
+
+func (m *testMaze) Width() int  { return len(m.rooms[0]) }
+func (m *testMaze) Height() int { return len(m.rooms) }
+
+func (m *testMaze) GetRoom(x, y int) (*Room, error) {
+	if x < 0 || y < 0 || x >= m.Width() || y >= m.Height() {
+		return nil, errors.New("room outside of the maze")
+	}
+	return &m.rooms[y][x], nil
+}
+
+func (m *testMaze) SetStartPoint(x, y int) error {
+	r, err := m.GetRoom(x, y)
+	if err != nil {
+		return err
+	}
+	r.Start = true
+	m.icarus = Coordinate{x, y}
+	return nil
+}
+
+func (m *testMaze) SetTreasure(x, y int) error {
+	r, err := m.GetRoom(x, y)
+	if err != nil {
+		return err
+	}
+	r.Treasure = true
+	return nil
+}
+
+func (m *testMaze) LookAround() (Survey, error) { return m.Discover(m.icarus.X, m.icarus.Y) }
+
+func (m *testMaze) Discover(x, y int) (Survey, error) {
+	r, err := m.GetRoom(x, y)
+	if err != nil {
+		return Survey{}, err
+	}
+	return r.Walls, nil
+}
+
+func (m *testMaze) Icarus() (int, int) { return m.icarus.X, m.icarus.Y }
+
+func (m *testMaze) move(dir int) error {
+	s, err := m.LookAround()
+	if err != nil {
+		return err
+	}
+	if s.hasWall(dir) {
+		return errors.New("Can't walk through walls")
+	}
+	m.icarus = step(m.icarus, dir)
+	return nil
+}
+
+func (m *testMaze) MoveLeft() error  { return m.move(W) }
+func (m *testMaze) MoveRight() error { return m.move(E) }
+func (m *testMaze) MoveUp() error    { return m.move(N) }
+func (m *testMaze) MoveDown() error  { return m.move(S) }
+
+func TestValidate(t *testing.T) {
+	const (
+		// the start reaches the treasure through the top right room, the bottom left one is walled in
+		walledIn = "_______\n|⏂__  |\n|__|⏅_|\n"
+		// every room leads to the others through a single way
+		perfect = "_______\n|⏀ _  |\n|__|⏅_|\n"
+		// no inner wall, so there's a way around
+		loop = "_______\n|⏀ _  |\n|___⏅_|\n"
+	)
+	tests := []struct {
+		name   string
+		maze   string
+		change func(m *testMaze)
+		// checks failing, in the order they're found
+		checks    []string
+		reachable int
+		solvable  bool
+		loops     int
+		perfect   bool
+	}{
+		{name: "walled in", maze: walledIn, reachable: 3, solvable: true},
+		{name: "perfect", maze: perfect, reachable: 4, solvable: true, perfect: true},
+		{name: "loop", maze: loop, reachable: 4, solvable: true, loops: 1},
+		{
+			name:      "unreachable treasure",
+			maze:      "_______\n|⏂_|  |\n|__|⏅_|\n",
+			checks:    []string{CheckReachable},
+			reachable: 1,
+		},
+		{
+			name:      "one sided wall",
+			maze:      perfect,
+			change:    func(m *testMaze) { m.rooms[0][1].Walls.Left = true },
+			checks:    []string{CheckSymmetry},
+			reachable: 4,
+			solvable:  true,
+		},
+		{
+			name:      "open boundary",
+			maze:      perfect,
+			change:    func(m *testMaze) { m.rooms[0][1].Walls.Top = false },
+			checks:    []string{CheckBoundary},
+			reachable: 4,
+			solvable:  true,
+			perfect:   true,
+		},
+		{
+			name:      "two starts",
+			maze:      perfect,
+			change:    func(m *testMaze) { m.rooms[0][1].Start = true },
+			checks:    []string{CheckStart},
+			reachable: 4,
+			solvable:  true,
+			perfect:   true,
+		},
+		{
+			name:      "no treasure",
+			maze:      perfect,
+			change:    func(m *testMaze) { m.rooms[1][1].Treasure = false },
+			checks:    []string{CheckTreasure},
+			reachable: 4,
+			perfect:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := parseTestMaze(t, tt.maze)
+			if tt.change != nil {
+				tt.change(m)
+			}
+			r, err := Validate(m)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			checks := []string{}
+			for _, f := range r.Findings {
+				checks = append(checks, f.Check)
+			}
+			want := tt.checks
+			if want == nil {
+				want = []string{}
+			}
+			if !reflect.DeepEqual(checks, want) {
+				t.Errorf("findings %v, want checks %v", r.Findings, want)
+			}
+			if r.OK() != (len(want) == 0) {
+				t.Errorf("OK() = %v with findings %v", r.OK(), r.Findings)
+			}
+			if r.Reachable != tt.reachable {
+				t.Errorf("Reachable = %d, want %d", r.Reachable, tt.reachable)
+			}
+			if r.Solvable != tt.solvable {
+				t.Errorf("Solvable = %v, want %v", r.Solvable, tt.solvable)
+			}
+			if r.Loops != tt.loops {
+				t.Errorf("Loops = %d, want %d", r.Loops, tt.loops)
+			}
+			if r.Perfect != tt.perfect {
+				t.Errorf("Perfect = %v, want %v", r.Perfect, tt.perfect)
+			}
+		})
+	}
+}
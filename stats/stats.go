@@ -0,0 +1,168 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+// Package stats summarizes the results of solving many laybrinths.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// Run is the result of solving a single laybrinth
+type Run struct {
+	Steps int `json:"steps"`
+	// Optimal is the length of the shortest path to the treasure, zero if unknown
+	Optimal int `json:"optimal"`
+}
+
+// Ratio of the steps taken to the optimal ones, 1 is a perfect run
+func (r Run) Ratio() float64 {
+	return float64(r.Steps) / float64(r.Optimal)
+}
+
+// Distribution describes a set of values
+type Distribution struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	StdDev float64 `json:"stddev"`
+}
+
+// Summary of the runs
+type Summary struct {
+	Solved int           `json:"solved"`
+	Failed int           `json:"failed"`
+	Steps  *Distribution `json:"steps,omitempty"`
+	// Ratio of the steps to the optimal path, for the runs where it's known
+	Ratio *Distribution `json:"ratio,omitempty"`
+	Runs  []Run         `json:"runs"`
+}
+
+// Summarize the solved runs, and the number of failed ones
+func Summarize(runs []Run, failed int) Summary {
+	s := Summary{Solved: len(runs), Failed: failed, Runs: runs}
+	steps := []float64{}
+	ratios := []float64{}
+	for _, r := range runs {
+		steps = append(steps, float64(r.Steps))
+		if r.Optimal > 0 {
+			ratios = append(ratios, r.Ratio())
+		}
+	}
+	s.Steps = Describe(steps)
+	s.Ratio = Describe(ratios)
+	return s
+}
+
+// Describe the distribution of the values, nil if there aren't any
+func Describe(values []float64) *Distribution {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	total := 0.0
+	for _, v := range sorted {
+		total += v
+	}
+	mean := total / float64(len(sorted))
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(sorted))
+
+	return &Distribution{
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		Median: Percentile(sorted, 50),
+		P90:    Percentile(sorted, 90),
+		P95:    Percentile(sorted, 95),
+		P99:    Percentile(sorted, 99),
+		StdDev: math.Sqrt(variance),
+	}
+}
+
+// Percentile p, between 0 and 100, of sorted values.
+// It interpolates linearly between the closest ranks.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo < 0 {
+		return sorted[0]
+	}
+	if hi >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// WriteTable writes the summary for humans
+func (s Summary) WriteTable(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Labyrinth solved %d times, failed %d times after running out of steps\n", s.Solved, s.Failed); err != nil {
+		return err
+	}
+	if s.Steps == nil {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tsteps\tsteps/optimal\t")
+	row := func(name string, steps, ratio float64) {
+		if s.Ratio == nil {
+			fmt.Fprintf(tw, "%s\t%.1f\t-\t\n", name, steps)
+		} else {
+			fmt.Fprintf(tw, "%s\t%.1f\t%.2f\t\n", name, steps, ratio)
+		}
+	}
+	ratio := s.Ratio
+	if ratio == nil {
+		ratio = &Distribution{}
+	}
+	row("min", s.Steps.Min, ratio.Min)
+	row("median", s.Steps.Median, ratio.Median)
+	row("mean", s.Steps.Mean, ratio.Mean)
+	row("p90", s.Steps.P90, ratio.P90)
+	row("p95", s.Steps.P95, ratio.P95)
+	row("p99", s.Steps.P99, ratio.P99)
+	row("max", s.Steps.Max, ratio.Max)
+	row("stddev", s.Steps.StdDev, ratio.StdDev)
+	return tw.Flush()
+}
+
+// WriteJSON writes the summary for machines
+func (s Summary) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright © 2015 Steve Francia <spf@spf13.com>.
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+//
+
+package stats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"no values", nil, 50, 0},
+		{"single value", []float64{7}, 90, 7},
+		{"minimum", []float64{1, 2, 3, 4}, 0, 1},
+		{"maximum", []float64{1, 2, 3, 4}, 100, 4},
+		{"median of odd count", []float64{1, 2, 9}, 50, 2},
+		{"median of even count", []float64{1, 2, 3, 4}, 50, 2.5},
+		{"between ranks", []float64{10, 20, 30, 40, 50}, 90, 46},
+		{"below range", []float64{1, 2}, -10, 1},
+		{"above range", []float64{1, 2}, 150, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.sorted, tt.p); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   *Distribution
+	}{
+		{"no values", nil, nil},
+		{
+			"single value",
+			[]float64{3},
+			&Distribution{Min: 3, Max: 3, Mean: 3, Median: 3, P90: 3, P95: 3, P99: 3},
+		},
+		{
+			"unsorted values",
+			[]float64{4, 8, 2, 6},
+			&Distribution{Min: 2, Max: 8, Mean: 5, Median: 5, P90: 7.4, P95: 7.7, P99: 7.94, StdDev: math.Sqrt(5)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Describe(tt.values)
+			if got == nil || tt.want == nil {
+				if got != tt.want {
+					t.Fatalf("Describe(%v) = %+v, want %+v", tt.values, got, tt.want)
+				}
+				return
+			}
+			// compare with some tolerance for the interpolated values
+			g, w := reflect.ValueOf(*got), reflect.ValueOf(*tt.want)
+			for i := 0; i < g.NumField(); i++ {
+				if math.Abs(g.Field(i).Float()-w.Field(i).Float()) > 1e-9 {
+					t.Errorf("Describe(%v).%s = %v, want %v", tt.values, g.Type().Field(i).Name, g.Field(i).Float(), w.Field(i).Float())
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeKeepsValues(t *testing.T) {
+	values := []float64{3, 1, 2}
+	Describe(values)
+	if !reflect.DeepEqual(values, []float64{3, 1, 2}) {
+		t.Errorf("Describe sorted its input in place: %v", values)
+	}
+}